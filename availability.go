@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Case_study/models"
+)
+
+type AbsenceJSON struct {
+	ID         int    `json:"id,omitempty"`
+	PlayerID   int    `json:"player_id"`
+	PlayerName string `json:"player_name,omitempty"`
+	TeamID     int    `json:"team_id"`
+	Kind       string `json:"kind"`
+	FromWeek   int    `json:"from_week"`
+	Weeks      int    `json:"weeks"`
+	Reason     string `json:"reason,omitempty"`
+	// MatchID is set for absences that come from a match; only manual
+	// ones can be deleted
+	MatchID int `json:"match_id,omitempty"`
+	// ServedWeeks are the weeks of the fixtures a ban from a match covers
+	ServedWeeks []int `json:"served_weeks,omitempty"`
+}
+
+func absenceToJSON(a models.Absence, teams []models.Team) AbsenceJSON {
+	result := AbsenceJSON{
+		ID:          a.ID,
+		PlayerID:    a.PlayerID,
+		TeamID:      a.TeamID,
+		Kind:        string(a.Kind),
+		FromWeek:    a.FromWeek,
+		Weeks:       a.Weeks,
+		Reason:      a.Reason,
+		MatchID:     a.MatchID,
+		ServedWeeks: a.ServedWeeks,
+	}
+	for _, p := range getTeamByID(teams, a.TeamID).Players {
+		if p.ID == a.PlayerID {
+			result.PlayerName = p.Name
+		}
+	}
+	return result
+}
+
+// playFixture simulates a match with sim and the players available in its
+// week. sim is bound to the league once per request by the caller, so a
+// fitted model is not refitted on results simulated in the same request.
+func playFixture(league models.League, avail models.Availability, sim models.MatchSimulator, m *models.Match) {
+	teams := avail.Teams(league, m.Week)
+	models.PlayMatch(m, getTeamByID(teams, m.HomeTeamID), getTeamByID(teams, m.AwayTeamID), sim)
+}
+
+// availability lists the players ruled out in ?week= (default the next
+// week to be played) by injury or suspension, team by team
+func availability(w http.ResponseWriter, r *http.Request) {
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	week := nextUnplayedWeek(league.Matches)
+	if v := r.URL.Query().Get("week"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "week must be a positive integer", 400)
+			return
+		}
+		week = n
+	}
+	avail, err := models.LoadAvailability(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	absences := avail.Absences(league.Matches)
+	teams := []map[string]interface{}{}
+	for _, t := range league.Teams {
+		out := []AbsenceJSON{}
+		for _, a := range absences {
+			if a.TeamID == t.ID && a.Covers(week) {
+				out = append(out, absenceToJSON(a, league.Teams))
+			}
+		}
+		teams = append(teams, map[string]interface{}{
+			"team_id":     t.ID,
+			"team_name":   t.Name,
+			"unavailable": out,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"week":  week,
+		"teams": teams,
+	})
+}
+
+// playerAbsences serves /players/{id}/absences: GET lists the player's
+// absences this season, POST rules the player out by hand and
+// DELETE /players/{id}/absences/{absence_id} removes a manual absence
+func playerAbsences(w http.ResponseWriter, r *http.Request, player models.Player, rest []string) {
+	team, err := models.SQLiteTeamRepository{}.GetTeamByID(player.TeamID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	league, err := leagueRepo.GetLeague(team.LeagueID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if len(rest) == 1 {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(rest[0])
+		if err != nil {
+			http.Error(w, "Invalid absence ID", 400)
+			return
+		}
+		err = models.SQLiteAbsenceRepository{}.DeleteAbsence(player.ID, id)
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Absence not found", 404)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		avail, err := models.LoadAvailability(league.SeasonID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		result := []AbsenceJSON{}
+		for _, a := range avail.Absences(league.Matches) {
+			if a.PlayerID == player.ID {
+				result = append(result, absenceToJSON(a, league.Teams))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	case http.MethodPost:
+		var req AbsenceJSON
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", 400)
+			return
+		}
+		absence := models.Absence{
+			PlayerID: player.ID,
+			TeamID:   player.TeamID,
+			SeasonID: league.SeasonID,
+			Kind:     models.AbsenceKind(strings.ToLower(req.Kind)),
+			FromWeek: req.FromWeek,
+			Weeks:    req.Weeks,
+			Reason:   strings.TrimSpace(req.Reason),
+		}
+		if absence.FromWeek == 0 {
+			absence.FromWeek = nextUnplayedWeek(league.Matches)
+		}
+		if err := absence.Validate(); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		absence.ID, err = models.SQLiteAbsenceRepository{}.CreateAbsence(absence)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(absenceToJSON(absence, league.Teams))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
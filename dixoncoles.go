@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"Case_study/models"
+)
+
+type DixonColesTeamJSON struct {
+	TeamID   int     `json:"team_id"`
+	TeamName string  `json:"team_name"`
+	Attack   float64 `json:"attack"`
+	Defence  float64 `json:"defence"`
+}
+
+// dixonColesFit fits the Dixon-Coles model to the league's results so far,
+// including earlier seasons, and returns its parameters. attack and
+// defence are multipliers, 1 being an average team: a side's expected
+// goals are average_goals times its attack over the opponent's defence,
+// times home_advantage at home. The fit takes the dixon-coles parameters
+// of the configured simulator, which ?half_life=, ?shrinkage= and
+// ?average_goals= override, and ?from_week= fits the results up to that
+// week.
+func dixonColesFit(w http.ResponseWriter, r *http.Request) {
+	config, _, err := requestSimulatorConfig(r, "dixon-coles")
+	if err == nil && config.Model != "dixon-coles" {
+		err = fmt.Errorf("model must be dixon-coles")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	_, values, err := simulators.Resolve(config)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	opts := models.DixonColesOptionsFromParams(values)
+	league, _, ok := estimateLeague(w, r, -1)
+	if !ok {
+		return
+	}
+	params, err := models.FitLeague(league, opts)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	result := map[string]interface{}{
+		"half_life": opts.HalfLife,
+		"shrinkage": opts.Shrinkage,
+		"fitted":    params != nil,
+	}
+	if params != nil {
+		teams := []DixonColesTeamJSON{}
+		for _, t := range league.Teams {
+			teams = append(teams, DixonColesTeamJSON{
+				TeamID:   t.ID,
+				TeamName: t.Name,
+				Attack:   math.Exp(params.Attack[t.ID]),
+				Defence:  math.Exp(params.Defence[t.ID]),
+			})
+		}
+		result["matches"] = params.Matches
+		result["weight"] = params.Weight
+		result["average_goals"] = math.Exp(params.Intercept)
+		result["home_advantage"] = math.Exp(params.HomeAdvantage)
+		result["rho"] = params.Rho
+		result["log_likelihood"] = params.LogLikelihood
+		result["iterations"] = params.Iterations
+		result["converged"] = params.Converged
+		result["teams"] = teams
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
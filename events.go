@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Case_study/models"
+)
+
+type MatchEventJSON struct {
+	ID       int    `json:"id,omitempty"`
+	Minute   int    `json:"minute"`
+	Type     string `json:"type"`
+	TeamID   int    `json:"team_id"`
+	PlayerID int    `json:"player_id,omitempty"`
+	Player   string `json:"player,omitempty"`
+	// RelatedPlayerID is the assist for a goal or the substitute coming on
+	RelatedPlayerID int    `json:"related_player_id,omitempty"`
+	RelatedPlayer   string `json:"related_player,omitempty"`
+	Weeks           int    `json:"weeks,omitempty"`
+	Extra           string `json:"extra,omitempty"`
+}
+
+func eventToJSON(e models.MatchEvent) MatchEventJSON {
+	return MatchEventJSON{
+		ID:              e.ID,
+		Minute:          e.Minute,
+		Type:            string(e.Type),
+		TeamID:          e.TeamID,
+		PlayerID:        e.PlayerID,
+		Player:          e.Player,
+		RelatedPlayerID: e.RelatedPlayerID,
+		RelatedPlayer:   e.RelatedPlayer,
+		Weeks:           e.Weeks,
+		Extra:           e.Extra,
+	}
+}
+
+func eventsFromJSON(matchID int, events []MatchEventJSON) []models.MatchEvent {
+	result := []models.MatchEvent{}
+	for _, e := range events {
+		result = append(result, models.MatchEvent{
+			MatchID:         matchID,
+			Minute:          e.Minute,
+			Type:            models.EventType(e.Type),
+			TeamID:          e.TeamID,
+			PlayerID:        e.PlayerID,
+			Player:          strings.TrimSpace(e.Player),
+			RelatedPlayerID: e.RelatedPlayerID,
+			RelatedPlayer:   strings.TrimSpace(e.RelatedPlayer),
+			Weeks:           e.Weeks,
+			Extra:           strings.TrimSpace(e.Extra),
+		})
+	}
+	return result
+}
+
+// matchDetail serves /match/{id}: GET returns the match with its timeline
+// and PUT edits the result
+func matchDetail(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		editMatchResult(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Path[len("/match/"):])
+	if err != nil {
+		http.Error(w, "Invalid match ID", 400)
+		return
+	}
+	match, err := models.SQLiteMatchRepository{}.GetMatchByID(id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Match not found", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	events, err := models.SQLiteEventRepository{}.GetEventsByMatch(id)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	timeline := []MatchEventJSON{}
+	for _, e := range events {
+		timeline = append(timeline, eventToJSON(e))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"match":    matchToJSON(match),
+		"timeline": timeline,
+	})
+}
+
+// eventStats reports goals by half, cards and late winners for the
+// league's current season. ?late_minute= sets when a winner counts as late
+// (default 80).
+func eventStats(w http.ResponseWriter, r *http.Request) {
+	lateMinute := 80
+	if v := r.URL.Query().Get("late_minute"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > models.MatchMinutes {
+			http.Error(w, "late_minute must be between 1 and 90", 400)
+			return
+		}
+		lateMinute = n
+	}
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	events, err := models.SQLiteEventRepository{}.GetEventsBySeason(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	stats := models.ComputeEventStats(league.Teams, league.Matches, events, lateMinute)
+	teamNames := make(map[int]string)
+	for _, t := range league.Teams {
+		teamNames[t.ID] = t.Name
+	}
+	teams := []map[string]interface{}{}
+	for _, ts := range stats.Teams {
+		teams = append(teams, map[string]interface{}{
+			"team_id":               ts.TeamID,
+			"team_name":             teamNames[ts.TeamID],
+			"first_half_goals":      ts.FirstHalfGoals,
+			"second_half_goals":     ts.SecondHalfGoals,
+			"yellow_cards":          ts.YellowCards,
+			"red_cards":             ts.RedCards,
+			"late_winners":          ts.LateWinners,
+			"late_winners_conceded": ts.LateWinnersConceded,
+		})
+	}
+	lateWinners := []map[string]interface{}{}
+	for _, lw := range stats.LateWinners {
+		lateWinners = append(lateWinners, map[string]interface{}{
+			"match_id":  lw.MatchID,
+			"team_id":   lw.TeamID,
+			"team_name": teamNames[lw.TeamID],
+			"minute":    lw.Minute,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"late_minute":       lateMinute,
+		"first_half_goals":  stats.FirstHalfGoals,
+		"second_half_goals": stats.SecondHalfGoals,
+		"teams":             teams,
+		"late_winners":      lateWinners,
+	})
+}
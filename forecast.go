@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Case_study/models"
+)
+
+const (
+	defaultSimulations = 1000
+	maxSimulations     = 100000
+)
+
+type ZoneJSON struct {
+	Name string `json:"name"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+type TeamForecastJSON struct {
+	TeamID         int                `json:"team_id"`
+	TeamName       string             `json:"team_name"`
+	Positions      []float64          `json:"positions"`
+	ExpectedPoints float64            `json:"expected_points"`
+	PointsInterval [2]int             `json:"points_interval_95"`
+	Zones          map[string]float64 `json:"zones"`
+}
+
+func forecastToJSON(forecast *models.Forecast, zones []models.Zone) []TeamForecastJSON {
+	teams := []TeamForecastJSON{}
+	for _, tf := range forecast.Summary(zones) {
+		teams = append(teams, TeamForecastJSON{
+			TeamID:         tf.TeamID,
+			TeamName:       tf.TeamName,
+			Positions:      tf.Positions,
+			ExpectedPoints: tf.ExpectedPoints,
+			PointsInterval: [2]int{tf.PointsLower, tf.PointsUpper},
+			Zones:          tf.Zones,
+		})
+	}
+	return teams
+}
+
+func zonesToJSON(zones []models.Zone) []ZoneJSON {
+	result := []ZoneJSON{}
+	for _, z := range zones {
+		result = append(result, ZoneJSON{Name: z.Name, From: z.From, To: z.To})
+	}
+	return result
+}
+
+// zonesFromRequest reads ?zones=, defaulting to the title and bottom place
+func zonesFromRequest(r *http.Request, teams int) ([]models.Zone, error) {
+	if v := r.URL.Query().Get("zones"); v != "" {
+		return models.ParseZones(v, teams)
+	}
+	return models.DefaultZones(teams), nil
+}
+
+// monteCarloFromRequest builds the simulation engine from ?simulations=,
+// ?seed= and the simulator the request selects, drawing a seed from the
+// clock when none is given
+func monteCarloFromRequest(r *http.Request, simulations int) (models.MonteCarlo, error) {
+	q := r.URL.Query()
+	mc := models.MonteCarlo{Simulations: simulations, Seed: time.Now().UnixNano()}
+	sim, err := simulatorFromRequest(r)
+	if err != nil {
+		return mc, err
+	}
+	mc.Simulator = sim
+	if v := q.Get("simulations"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSimulations {
+			return mc, fmt.Errorf("simulations must be between 1 and %d", maxSimulations)
+		}
+		mc.Simulations = n
+	}
+	if v := q.Get("seed"); v != "" {
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return mc, errors.New("Invalid seed")
+		}
+		mc.Seed = seed
+	}
+	return mc, nil
+}
+
+// fromWeekParam reads ?from_week=, returning fallback when it is absent
+func fromWeekParam(r *http.Request, fallback int) (int, error) {
+	v := r.URL.Query().Get("from_week")
+	if v == "" {
+		return fallback, nil
+	}
+	week, err := strconv.Atoi(v)
+	if err != nil || week < 0 {
+		return 0, errors.New("from_week must be a week number")
+	}
+	return week, nil
+}
+
+// rewindLeague treats every result after the given week as unplayed and
+// replays the ratings up to it, so estimates see the league as it stood
+// then. A negative week leaves the league as it is.
+func rewindLeague(league models.League, week int) (models.League, error) {
+	if week < 0 {
+		return league, nil
+	}
+	league.Teams = append([]models.Team(nil), league.Teams...)
+	league.Matches = append([]models.Match(nil), league.Matches...)
+	for i := range league.Matches {
+		if league.Matches[i].Week > week {
+			league.Matches[i].SetStatus(models.StatusScheduled)
+		}
+	}
+	start, err := models.SQLiteRatingRepository{}.GetStartRatings(league.SeasonID)
+	if err != nil {
+		return league, err
+	}
+	rateTeams(&league, start)
+	return league, nil
+}
+
+// estimateLeague loads the league a request is scoped to as it stood after
+// ?from_week=, or fallbackWeek when the parameter is absent
+func estimateLeague(w http.ResponseWriter, r *http.Request, fallbackWeek int) (models.League, int, bool) {
+	fromWeek, err := fromWeekParam(r, fallbackWeek)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return models.League{}, 0, false
+	}
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return models.League{}, 0, false
+	}
+	league, err = rewindLeague(league, fromWeek)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return models.League{}, 0, false
+	}
+	avail, err := models.LoadAvailability(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return models.League{}, 0, false
+	}
+	league.Absences = avail.Absences(league.Matches)
+	return league, fromWeek, true
+}
+
+// positionProbabilities simulates the rest of the season many times and
+// reports how often every team finishes in each position and zone.
+// ?simulations= sets the number of runs, ?seed= makes them reproducible and
+// ?zones=title:1,europe:2-3,relegation:4 names the position ranges to report
+// and ?from_week= starts from the table after that week.
+func positionProbabilities(w http.ResponseWriter, r *http.Request) {
+	mc, err := monteCarloFromRequest(r, defaultSimulations)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	league, _, ok := estimateLeague(w, r, -1)
+	if !ok {
+		return
+	}
+	zones, err := zonesFromRequest(r, len(league.Teams))
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	forecast := mc.Run(league)
+	teams := forecastToJSON(forecast, zones)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"simulations": mc.Simulations,
+		"seed":        mc.Seed,
+		"zones":       zonesToJSON(zones),
+		"teams":       teams,
+	})
+}
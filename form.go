@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"Case_study/models"
+)
+
+type FormResultJSON struct {
+	MatchID      int    `json:"match_id"`
+	Week         int    `json:"week"`
+	OpponentID   int    `json:"opponent_id"`
+	Opponent     string `json:"opponent"`
+	Venue        string `json:"venue"`
+	GoalsFor     int    `json:"goals_for"`
+	GoalsAgainst int    `json:"goals_against"`
+	Outcome      string `json:"outcome"`
+	Points       int    `json:"points"`
+}
+
+type RollingPointsJSON struct {
+	Week          int     `json:"week"`
+	PointsPerGame float64 `json:"points_per_game"`
+}
+
+// teamForm returns a team's W/D/L string and points per game over its last
+// ?window= matches (default the -form-window setting), the same results
+// and rolling points per game after each match, and the strength factor
+// the form simulator would give it
+func teamForm(w http.ResponseWriter, r *http.Request, team models.Team) {
+	window := formWindow
+	if v := r.URL.Query().Get("window"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "window must be a positive integer", 400)
+			return
+		}
+		window = n
+	}
+	league, err := leagueRepo.GetLeague(team.LeagueID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	scoring := league.Scoring
+	if scoring == (models.ScoringRules{}) {
+		scoring = models.DefaultScoringRules
+	}
+	form := models.CalculateForm(league.Matches, team.ID, window, scoring)
+	results := []FormResultJSON{}
+	for _, res := range form.Results {
+		opponent, venue := res.Match.AwayTeamID, "home"
+		if res.Match.AwayTeamID == team.ID {
+			opponent, venue = res.Match.HomeTeamID, "away"
+		}
+		results = append(results, FormResultJSON{
+			MatchID:      res.Match.ID,
+			Week:         res.Match.Week,
+			OpponentID:   opponent,
+			Opponent:     getTeamByID(league.Teams, opponent).Name,
+			Venue:        venue,
+			GoalsFor:     res.GoalsFor,
+			GoalsAgainst: res.GoalsAgainst,
+			Outcome:      res.Outcome,
+			Points:       res.Points,
+		})
+	}
+	rolling := []RollingPointsJSON{}
+	all := models.CalculateForm(league.Matches, team.ID, 0, scoring)
+	for i, ppg := range models.RollingPointsPerGame(league.Matches, team.ID, window, scoring) {
+		rolling = append(rolling, RollingPointsJSON{Week: all.Results[i].Match.Week, PointsPerGame: ppg})
+	}
+	result := map[string]interface{}{
+		"team_id":         team.ID,
+		"team_name":       team.Name,
+		"window":          window,
+		"form":            form.String(),
+		"points":          form.Points,
+		"points_per_game": form.PointsPerGame(),
+		"results":         results,
+		"rolling":         rolling,
+	}
+	if f, ok := matchSim.(models.FormMatchSimulator); ok {
+		result["strength_factor"] = 1 + f.Weight*form.Score(scoring)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"Case_study/models"
+)
+
+const defaultLeaderboardLimit = 10
+
+type PlayerStatsJSON struct {
+	PlayerID    int    `json:"player_id,omitempty"`
+	TeamID      int    `json:"team_id"`
+	TeamName    string `json:"team_name"`
+	Name        string `json:"name"`
+	Value       int    `json:"value"`
+	Appearances int    `json:"appearances"`
+	Minutes     int    `json:"minutes"`
+	Goals       int    `json:"goals"`
+	Assists     int    `json:"assists"`
+	CleanSheets int    `json:"clean_sheets"`
+	YellowCards int    `json:"yellow_cards"`
+	RedCards    int    `json:"red_cards"`
+}
+
+func leaderboardToJSON(board []models.PlayerStats, category models.LeaderboardCategory, teams []models.Team) []PlayerStatsJSON {
+	result := []PlayerStatsJSON{}
+	for _, s := range board {
+		result = append(result, PlayerStatsJSON{
+			PlayerID:    s.PlayerID,
+			TeamID:      s.TeamID,
+			TeamName:    getTeamByID(teams, s.TeamID).Name,
+			Name:        s.Name,
+			Value:       category.Value(s),
+			Appearances: s.Appearances,
+			Minutes:     s.Minutes,
+			Goals:       s.Goals,
+			Assists:     s.Assists,
+			CleanSheets: s.CleanSheets,
+			YellowCards: s.YellowCards,
+			RedCards:    s.RedCards,
+		})
+	}
+	return result
+}
+
+// leaderboardData loads the league and the season's events and reads
+// ?limit= (default 10, 0 for everyone)
+func leaderboardData(w http.ResponseWriter, r *http.Request) (models.League, []models.MatchEvent, int, bool) {
+	limit := defaultLeaderboardLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "limit must be a non-negative integer", 400)
+			return models.League{}, nil, 0, false
+		}
+		limit = n
+	}
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return league, nil, 0, false
+	}
+	events, err := models.SQLiteEventRepository{}.GetEventsBySeason(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return league, nil, 0, false
+	}
+	return league, events, limit, true
+}
+
+// leaderboards returns top scorers, assists, clean sheets, cards and
+// minutes played for the current season, built from the stored match
+// events. ?week= gives the leaderboards as they stood after that week.
+func leaderboards(w http.ResponseWriter, r *http.Request) {
+	week := 0
+	if v := r.URL.Query().Get("week"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "week must be a positive integer", 400)
+			return
+		}
+		week = n
+	}
+	league, events, limit, ok := leaderboardData(w, r)
+	if !ok {
+		return
+	}
+	stats := models.ComputePlayerStats(league.Teams, league.Matches, events, week)
+	boards := make(map[string]interface{})
+	for _, c := range models.LeaderboardCategories {
+		boards[string(c)] = leaderboardToJSON(models.Leaderboard(stats, c, limit), c, league.Teams)
+	}
+	result := map[string]interface{}{"leaderboards": boards}
+	if week > 0 {
+		result["week"] = week
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// leaderboardHistory returns one leaderboard as it stood after each week
+// with a completed match, chosen with ?category= (default scorers)
+func leaderboardHistory(w http.ResponseWriter, r *http.Request) {
+	category := models.LeaderboardScorers
+	if v := r.URL.Query().Get("category"); v != "" {
+		c, err := models.ParseLeaderboardCategory(v)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		category = c
+	}
+	league, events, limit, ok := leaderboardData(w, r)
+	if !ok {
+		return
+	}
+	lastWeek := 0
+	for _, m := range league.Matches {
+		if m.Status == models.StatusCompleted && m.Week > lastWeek {
+			lastWeek = m.Week
+		}
+	}
+	snapshots := []map[string]interface{}{}
+	for week := 1; week <= lastWeek; week++ {
+		stats := models.ComputePlayerStats(league.Teams, league.Matches, events, week)
+		snapshots = append(snapshots, map[string]interface{}{
+			"week":    week,
+			"leaders": leaderboardToJSON(models.Leaderboard(stats, category, limit), category, league.Teams),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"category": category,
+		"weeks":    snapshots,
+	})
+}
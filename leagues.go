@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Case_study/models"
+)
+
+// defaultLeagueID is the league served by the unscoped /league/* routes
+const defaultLeagueID = 1
+
+type leagueIDKey struct{}
+
+// leagueIDFromRequest returns the league a request is scoped to, falling
+// back to the default league for /league/* routes
+func leagueIDFromRequest(r *http.Request) int {
+	if id, ok := r.Context().Value(leagueIDKey{}).(int); ok {
+		return id
+	}
+	return defaultLeagueID
+}
+
+// leagueHandlers maps the action part of /leagues/{id}/{action} onto the
+// same handlers used by /league/{action}
+var leagueHandlers = map[string]http.HandlerFunc{
+	"table":                  getLeagueTable,
+	"next-week":              playNextWeek,
+	"play-all":               playAll,
+	"estimate":               estimateFinalTable,
+	"results-by-week":        resultsByWeek,
+	"after-week4-estimate":   afterWeek4Estimate,
+	"champion-estimation":    championEstimation,
+	"reset":                  resetLeague,
+	"generate-fixtures":      generateFixtures,
+	"ratings":                teamRatings,
+	"close-season":           closeSeason,
+	"tie-breakers":           tieBreakers,
+	"scoring-rules":          scoringRules,
+	"deductions":             pointDeductions,
+	"standings-check":        standingsCheck,
+	"position-probabilities": positionProbabilities,
+	"scenario":               whatIfScenario,
+	"replay-postponed":       replayPostponed,
+	"event-stats":            eventStats,
+	"leaderboards":           leaderboards,
+	"leaderboards/history":   leaderboardHistory,
+	"availability":           availability,
+	"dixon-coles":            dixonColesFit,
+}
+
+// leagueRoutes serves /leagues/{id}/{action} by running the matching
+// handler with the league ID stored in the request context
+func leagueRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/leagues/"):], "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid league ID", 400)
+		return
+	}
+	league, err := leagueRepo.GetLeague(id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "League not found", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if len(parts) == 1 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(leagueToJSON(league))
+		return
+	}
+	handler, ok := leagueHandlers[strings.Join(parts[1:], "/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler(w, r.WithContext(context.WithValue(r.Context(), leagueIDKey{}, id)))
+}
+
+type LeagueJSON struct {
+	ID       int        `json:"id"`
+	Name     string     `json:"name"`
+	SeasonID int        `json:"season_id,omitempty"`
+	Teams    []TeamJSON `json:"teams,omitempty"`
+}
+
+type TeamJSON struct {
+	ID       int     `json:"id"`
+	LeagueID int     `json:"league_id,omitempty"`
+	Name     string  `json:"name"`
+	Strength int     `json:"strength"`
+	Rating   float64 `json:"rating,omitempty"`
+}
+
+func leagueToJSON(l models.League) LeagueJSON {
+	result := LeagueJSON{ID: l.ID, Name: l.Name, SeasonID: l.SeasonID}
+	for _, t := range l.Teams {
+		result.Teams = append(result.Teams, TeamJSON{ID: t.ID, Name: t.Name, Strength: t.Strength})
+	}
+	return result
+}
+
+// leaguesHandler lists leagues on GET and creates one on POST
+func leaguesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		leagues, err := leagueRepo.GetAllLeagues()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		result := []LeagueJSON{}
+		for _, l := range leagues {
+			result = append(result, leagueToJSON(l))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	case http.MethodPost:
+		createLeague(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func createLeague(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name  string `json:"name"`
+		Teams []struct {
+			Name     string `json:"name"`
+			Strength int    `json:"strength"`
+		} `json:"teams"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		http.Error(w, "League name is required", 400)
+		return
+	}
+	var teams []models.Team
+	for _, t := range req.Teams {
+		team := models.Team{Name: strings.TrimSpace(t.Name), Strength: t.Strength}
+		if err := team.Validate(); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		teams = append(teams, team)
+	}
+	id, err := setupLeague(strings.TrimSpace(req.Name), teams)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	league, err := leagueRepo.GetLeague(id)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(leagueToJSON(league))
+}
+
+// setupLeague creates a league with its teams, a first season, fixtures and
+// starting ratings in one transaction, and returns the new league's ID
+func setupLeague(name string, teams []models.Team) (int, error) {
+	return leagueRepo.SetupLeague(name, teams, "Season 1")
+}
+
+// tieBreakers returns the league's tie-breaker chain on GET and replaces it
+// on PUT
+func tieBreakers(w http.ResponseWriter, r *http.Request) {
+	leagueID := leagueIDFromRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Order    []string `json:"order"`
+			LotsSeed int64    `json:"lots_seed"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", 400)
+			return
+		}
+		order, err := models.ParseTieBreakers(strings.Join(req.Order, ","))
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		if err := leagueRepo.UpdateTieBreakers(leagueID, order, req.LotsSeed); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	league, err := leagueRepo.GetLeague(leagueID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	order := []string{}
+	for _, tb := range league.TieBreakRules().Order {
+		order = append(order, string(tb))
+	}
+	available := []string{}
+	for _, tb := range models.AllTieBreakers {
+		available = append(available, string(tb))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"order":     order,
+		"lots_seed": league.LotsSeed,
+		"available": available,
+	})
+}
+
+type ScoringRulesJSON struct {
+	WinPoints           int `json:"win_points"`
+	DrawPoints          int `json:"draw_points"`
+	LossPoints          int `json:"loss_points"`
+	GoalsBonusThreshold int `json:"goals_bonus_threshold"`
+	GoalsBonusPoints    int `json:"goals_bonus_points"`
+	LosingBonusMargin   int `json:"losing_bonus_margin"`
+	LosingBonusPoints   int `json:"losing_bonus_points"`
+}
+
+// scoringRules returns the league's points system on GET and replaces it
+// on PUT
+func scoringRules(w http.ResponseWriter, r *http.Request) {
+	leagueID := leagueIDFromRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req ScoringRulesJSON
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", 400)
+			return
+		}
+		rules := models.ScoringRules(req)
+		if err := rules.Validate(); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		if err := leagueRepo.UpdateScoringRules(leagueID, rules); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		// Stored points were earned under the old rules
+		league, err := leagueRepo.GetLeague(leagueID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if err := rebuildStandings(&league); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rules, err := leagueRepo.GetScoringRules(leagueID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ScoringRulesJSON(rules))
+}
+
+type PointDeductionJSON struct {
+	ID       int    `json:"id"`
+	SeasonID int    `json:"season_id"`
+	TeamID   int    `json:"team_id"`
+	Points   int    `json:"points"`
+	Reason   string `json:"reason"`
+}
+
+// pointDeductions lists the current season's sanctions on GET, adds one on
+// POST and removes one on DELETE with ?id=
+func pointDeductions(w http.ResponseWriter, r *http.Request) {
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req PointDeductionJSON
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", 400)
+			return
+		}
+		if req.Points <= 0 {
+			http.Error(w, "Deducted points must be positive", 400)
+			return
+		}
+		if getTeamByID(league.Teams, req.TeamID).ID == 0 {
+			http.Error(w, "Team not found in this league", 400)
+			return
+		}
+		d := models.PointDeduction{SeasonID: league.SeasonID, TeamID: req.TeamID, Points: req.Points, Reason: req.Reason}
+		if _, err := leagueRepo.CreateDeduction(d); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "Invalid deduction ID", 400)
+			return
+		}
+		err = leagueRepo.DeleteDeduction(league.SeasonID, id)
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Deduction not found", 404)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	deductions, err := leagueRepo.GetDeductions(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	result := []PointDeductionJSON{}
+	for _, d := range deductions {
+		result = append(result, PointDeductionJSON(d))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// standingsCheck compares the stored standings with a table recalculated
+// from the matches. GET only reports differences; POST also rebuilds the
+// stored rows from the matches.
+func standingsCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	standingsRepo := models.SQLiteStandingsRepository{}
+	stored, err := standingsRepo.GetStandings(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	storedByTeam := make(map[int]models.LeagueTableEntry)
+	for _, e := range stored {
+		storedByTeam[e.TeamID] = e
+	}
+	type Difference struct {
+		TeamID     int                     `json:"team_id"`
+		TeamName   string                  `json:"team_name"`
+		Stored     models.LeagueTableEntry `json:"stored"`
+		Calculated models.LeagueTableEntry `json:"calculated"`
+	}
+	differences := []Difference{}
+	calculated := league.CalculateTable()
+	for _, e := range calculated {
+		if storedByTeam[e.TeamID] != e {
+			differences = append(differences, Difference{TeamID: e.TeamID, TeamName: e.TeamName, Stored: storedByTeam[e.TeamID], Calculated: e})
+		}
+	}
+	rebuilt := false
+	if r.Method == http.MethodPost {
+		if err := standingsRepo.Rebuild(league.SeasonID, calculated); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		rebuilt = true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"consistent":  len(differences) == 0,
+		"differences": differences,
+		"rebuilt":     rebuilt,
+	})
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"Case_study/models"
+)
+
+const (
+	defaultLiveDuration = 90 * time.Second
+	maxLiveDuration     = 10 * time.Minute
+)
+
+type LiveEventJSON struct {
+	Minute          int    `json:"minute"`
+	Type            string `json:"type"`
+	TeamID          int    `json:"team_id,omitempty"`
+	PlayerID        int    `json:"player_id,omitempty"`
+	Player          string `json:"player,omitempty"`
+	RelatedPlayerID int    `json:"related_player_id,omitempty"`
+	RelatedPlayer   string `json:"related_player,omitempty"`
+	Weeks           int    `json:"weeks,omitempty"`
+	HomeGoals       int    `json:"home_goals"`
+	AwayGoals       int    `json:"away_goals"`
+}
+
+// writeEvent sends one Server-Sent Event and flushes it to the client
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, name string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, payload)
+	flusher.Flush()
+}
+
+// liveMatch plays a match over ?duration= of wall-clock time (a Go
+// duration such as 90s or 3m, default 90s) and streams every event as it
+// happens. The match is live while it runs, each goal updates the stored
+// score and the final result is stored through UpdateMatch. If the client
+// goes away the rest of the match is played at once so it never stays live.
+func liveMatch(w http.ResponseWriter, r *http.Request, match models.Match) {
+	duration := defaultLiveDuration
+	if v := r.URL.Query().Get("duration"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 || d > maxLiveDuration {
+			http.Error(w, "duration must be between 0s and "+maxLiveDuration.String(), 400)
+			return
+		}
+		duration = d
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", 500)
+		return
+	}
+	if match.Played || match.Status == models.StatusLive {
+		http.Error(w, "Match has already been played or is live", http.StatusConflict)
+		return
+	}
+	league, i, ok := openMatchLeague(w, match)
+	if !ok {
+		return
+	}
+	m := league.Matches[i]
+	matchRepo := models.SQLiteMatchRepository{}
+	m.SetStatus(models.StatusLive)
+	err := matchRepo.UpdateMatch(m)
+	if errors.Is(err, models.ErrInvalidTransition) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	avail, err := models.LoadAvailability(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	teams := avail.Teams(league, m.Week)
+	home, away := getTeamByID(teams, m.HomeTeamID), getTeamByID(teams, m.AwayTeamID)
+	events := models.SimulateLive(home, away, models.BindSimulator(matchSim, league), rand.New(rand.NewSource(time.Now().UnixNano())))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	perMinute := duration / models.MatchMinutes
+	streaming := true
+	minute := 0
+	for k, e := range events {
+		if streaming && e.Minute > minute {
+			timer := time.NewTimer(time.Duration(e.Minute-minute) * perMinute)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				streaming = false
+			}
+		}
+		minute = e.Minute
+		if e.Type == models.EventGoal {
+			m.SetLiveScore(e.HomeGoals, e.AwayGoals)
+			m.Events = models.TimelineEvents(m.ID, events[:k+1])
+			if err := matchRepo.UpdateMatch(m); err != nil {
+				if streaming {
+					writeEvent(w, flusher, "error", map[string]string{"error": err.Error()})
+				}
+				return
+			}
+		}
+		if streaming {
+			writeEvent(w, flusher, string(e.Type), LiveEventJSON{
+				Minute:          e.Minute,
+				Type:            string(e.Type),
+				TeamID:          e.TeamID,
+				PlayerID:        e.PlayerID,
+				Player:          e.Player,
+				RelatedPlayerID: e.RelatedPlayerID,
+				RelatedPlayer:   e.RelatedPlayer,
+				Weeks:           e.Weeks,
+				HomeGoals:       e.HomeGoals,
+				AwayGoals:       e.AwayGoals,
+			})
+		}
+	}
+	final := events[len(events)-1]
+	m.SetResult(final.HomeGoals, final.AwayGoals)
+	m.Events = models.TimelineEvents(m.ID, events)
+	if err := finishLiveMatch(league.ID, m); err != nil {
+		if streaming {
+			writeEvent(w, flusher, "error", map[string]string{"error": err.Error()})
+		}
+		return
+	}
+	if streaming {
+		writeEvent(w, flusher, "result", matchToJSON(m))
+	}
+}
+
+// finishLiveMatch stores the final score, which moves the stored
+// standings, and the ratings replayed with it in one transaction. The
+// league is reloaded first, since weeks may have been played while the
+// match was live.
+func finishLiveMatch(leagueID int, m models.Match) error {
+	league, err := leagueRepo.GetLeague(leagueID)
+	if err != nil {
+		return err
+	}
+	for i := range league.Matches {
+		if league.Matches[i].ID == m.ID {
+			league.Matches[i] = m
+		}
+	}
+	if err := rateLeague(&league); err != nil {
+		return err
+	}
+	return models.SQLiteMatchRepository{}.UpdateMatchAndRatings(m, league.Teams, league.RatingHistory)
+}
@@ -2,16 +2,18 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
 	"log"
 	"math/rand"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"Case_study/models"
 	"Case_study/storage"
 	"os"
-	"database/sql"
 	"sort"
 )
 
@@ -24,6 +26,7 @@ type MatchJSON struct {
 	AwayGoals  *int   `json:"away_goals"`
 	Week       int    `json:"week"`
 	Played     bool   `json:"played"`
+	Status     string `json:"status"`
 }
 
 func matchToJSON(m models.Match) MatchJSON {
@@ -44,47 +47,82 @@ func matchToJSON(m models.Match) MatchJSON {
 		AwayGoals: ag,
 		Week: m.Week,
 		Played: m.Played,
+		Status: string(m.Status),
 	}
 }
 
 var (
 	leagueRepo models.LeagueRepository = models.SQLiteLeagueRepository{}
 	matchSim   models.MatchSimulator = models.BasicMatchSimulator{}
+	ratingEngine = models.NewEloRater()
+	// formWindow is the number of recent matches /teams/{id}/form and the
+	// form factor look at
+	formWindow = models.DefaultFormWindow
+	// simulators can be selected by name at startup and per request;
+	// simulatorConfig is the startup choice matchSim is built from
+	simulators      = models.DefaultSimulators()
+	simulatorConfig = models.SimulatorConfig{Model: "basic"}
 )
 
+// rateTeams recomputes every team's Elo rating from the season's results and
+// stores it on the in-memory teams so later simulations use it
+func rateTeams(league *models.League, start map[int]float64) []models.RatingPoint {
+	ratings, history := ratingEngine.Rate(league.Teams, league.Matches, start)
+	for i := range league.Teams {
+		league.Teams[i].Rating = ratings[league.Teams[i].ID]
+	}
+	return history
+}
+
+// rebuildStandings replaces the stored standings of the league's current
+// season with a table recalculated from its matches
+func rebuildStandings(league *models.League) error {
+	return models.SQLiteStandingsRepository{}.Rebuild(league.SeasonID, league.CalculateTable())
+}
+
+// rateLeague recomputes ratings from the season's starting point and keeps
+// the history on the league so UpdateLeague persists it with the results
+func rateLeague(league *models.League) error {
+	start, err := models.SQLiteRatingRepository{}.GetStartRatings(league.SeasonID)
+	if err != nil {
+		return err
+	}
+	league.RatingHistory = rateTeams(league, start)
+	return nil
+}
+
+// nextUnplayedWeek returns the earliest week that still has scheduled
+// matches, or 0 when none are left. Postponed and abandoned matches do not
+// hold the schedule back; they are replayed separately.
+func nextUnplayedWeek(matches []models.Match) int {
+	week := 0
+	for _, m := range matches {
+		if m.Status == models.StatusScheduled && (week == 0 || m.Week < week) {
+			week = m.Week
+		}
+	}
+	return week
+}
+
 func initDBAndData() {
 	dbFile := "league.db"
 	schemaFile := "sql/schema.sql"
 	if _, err := os.Stat(dbFile); os.IsNotExist(err) {
 		storage.InitDB(dbFile, schemaFile)
-		// Insert initial teams and matches
-		teamRepo := models.SQLiteTeamRepository{}
+		// Insert the default league with its initial teams and matches
 		teams := []models.Team{
 			{ID: 1, Name: "Lions", Strength: 90},
 			{ID: 2, Name: "Tigers", Strength: 80},
 			{ID: 3, Name: "Bears", Strength: 70},
 			{ID: 4, Name: "Wolves", Strength: 60},
 		}
-		for _, t := range teams {
-			_ = teamRepo.CreateTeam(t)
-		}
-		matchRepo := models.SQLiteMatchRepository{}
-		matches := []models.Match{
-			{ID: 1, HomeTeamID: 1, AwayTeamID: 2, Week: 1},
-			{ID: 2, HomeTeamID: 3, AwayTeamID: 4, Week: 1},
-			{ID: 3, HomeTeamID: 1, AwayTeamID: 3, Week: 2},
-			{ID: 4, HomeTeamID: 2, AwayTeamID: 4, Week: 2},
-			{ID: 5, HomeTeamID: 1, AwayTeamID: 4, Week: 3},
-			{ID: 6, HomeTeamID: 2, AwayTeamID: 3, Week: 3},
-			{ID: 7, HomeTeamID: 2, AwayTeamID: 1, Week: 4},
-			{ID: 8, HomeTeamID: 4, AwayTeamID: 3, Week: 4},
-			{ID: 9, HomeTeamID: 3, AwayTeamID: 1, Week: 5},
-			{ID: 10, HomeTeamID: 4, AwayTeamID: 2, Week: 5},
-			{ID: 11, HomeTeamID: 4, AwayTeamID: 1, Week: 6},
-			{ID: 12, HomeTeamID: 3, AwayTeamID: 2, Week: 6},
+		if _, err := setupLeague("Default League", teams); err != nil {
+			log.Fatalf("Failed to create league: %v", err)
 		}
-		for _, m := range matches {
-			_ = matchRepo.CreateMatch(m)
+		// Give the default teams made-up squads so the squad simulator
+		// and scorer statistics work out of the box
+		if err := generateSquads(teams, rand.New(rand.NewSource(1))); err != nil {
+			log.Fatalf("Failed to create squads: %v", err)
 		}
 	} else {
 		storage.InitDB(dbFile, schemaFile)
@@ -136,44 +174,11 @@ func getLatestPlayedWeek(matches []models.Match) int {
 	return maxWeek
 }
 
-// Helper to build standings with win/draw/loss
-func buildStandings(table []models.LeagueTableEntry, matches []models.Match) []map[string]interface{} {
-	teamStats := make(map[int]struct{W, D, L int})
-	for _, entry := range table {
-		teamStats[entry.TeamID] = struct{W, D, L int}{0, 0, 0}
-	}
-	for _, m := range matches {
-		if !m.Played || !m.HomeGoals.Valid || !m.AwayGoals.Valid {
-			continue
-		}
-		hg := int(m.HomeGoals.Int64)
-		ag := int(m.AwayGoals.Int64)
-		if hg > ag {
-			st := teamStats[m.HomeTeamID]
-			st.W++
-			teamStats[m.HomeTeamID] = st
-			st = teamStats[m.AwayTeamID]
-			st.L++
-			teamStats[m.AwayTeamID] = st
-		} else if hg < ag {
-			st := teamStats[m.AwayTeamID]
-			st.W++
-			teamStats[m.AwayTeamID] = st
-			st = teamStats[m.HomeTeamID]
-			st.L++
-			teamStats[m.HomeTeamID] = st
-		} else {
-			st := teamStats[m.HomeTeamID]
-			st.D++
-			teamStats[m.HomeTeamID] = st
-			st = teamStats[m.AwayTeamID]
-			st.D++
-			teamStats[m.AwayTeamID] = st
-		}
-	}
+// Helper to build standings with win/draw/loss. The table is already ranked
+// by CalculateTable, so its order is kept as is.
+func buildStandings(table []models.LeagueTableEntry) []map[string]interface{} {
 	var standings []map[string]interface{}
 	for _, entry := range table {
-		st := teamStats[entry.TeamID]
 		standings = append(standings, map[string]interface{}{
 			"TeamID": entry.TeamID,
 			"TeamName": entry.TeamName,
@@ -182,36 +187,29 @@ func buildStandings(table []models.LeagueTableEntry, matches []models.Match) []m
 			"GoalsAgainst": entry.GoalsAgainst,
 			"GoalDifference": entry.GoalDifference,
 			"MatchesPlayed": entry.MatchesPlayed,
-			"Wins": st.W,
-			"Draws": st.D,
-			"Losses": st.L,
+			"Wins": entry.Wins,
+			"Draws": entry.Draws,
+			"Losses": entry.Losses,
+			"PointsDeducted": entry.PointsDeducted,
 		})
 	}
-	// Sort standings: Points desc, GoalDifference desc, GoalsFor desc, TeamName asc
-	sort.SliceStable(standings, func(i, j int) bool {
-		si, sj := standings[i], standings[j]
-		if si["Points"].(int) != sj["Points"].(int) {
-			return si["Points"].(int) > sj["Points"].(int)
-		}
-		if si["GoalDifference"].(int) != sj["GoalDifference"].(int) {
-			return si["GoalDifference"].(int) > sj["GoalDifference"].(int)
-		}
-		if si["GoalsFor"].(int) != sj["GoalsFor"].(int) {
-			return si["GoalsFor"].(int) > sj["GoalsFor"].(int)
-		}
-		return si["TeamName"].(string) < sj["TeamName"].(string)
-	})
 	return standings
 }
 
 // Update getLeagueTable to use the helper
 func getLeagueTable(w http.ResponseWriter, r *http.Request) {
-	league, err := leagueRepo.GetLeague()
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	table := league.CalculateTable()
+	// Served from the stored standings rather than replaying every match
+	stored, err := models.SQLiteStandingsRepository{}.GetStandings(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	table := league.RankEntries(stored)
 	teamNames := make(map[int]string)
 	for _, t := range league.Teams {
 		teamNames[t.ID] = t.Name
@@ -228,44 +226,10 @@ func getLeagueTable(w http.ResponseWriter, r *http.Request) {
 		Wins           int    `json:"Wins"`
 		Draws          int    `json:"Draws"`
 		Losses         int    `json:"Losses"`
-	}
-	// Calculate win/draw/loss for each team
-	teamStats := make(map[int]struct{W, D, L int})
-	for _, t := range league.Teams {
-		teamStats[t.ID] = struct{W, D, L int}{0, 0, 0}
-	}
-	for _, m := range league.Matches {
-		if !m.Played || !m.HomeGoals.Valid || !m.AwayGoals.Valid {
-			continue
-		}
-		hg := int(m.HomeGoals.Int64)
-		ag := int(m.AwayGoals.Int64)
-		if hg > ag {
-			st := teamStats[m.HomeTeamID]
-			st.W++
-			teamStats[m.HomeTeamID] = st
-			st = teamStats[m.AwayTeamID]
-			st.L++
-			teamStats[m.AwayTeamID] = st
-		} else if hg < ag {
-			st := teamStats[m.AwayTeamID]
-			st.W++
-			teamStats[m.AwayTeamID] = st
-			st = teamStats[m.HomeTeamID]
-			st.L++
-			teamStats[m.HomeTeamID] = st
-		} else {
-			st := teamStats[m.HomeTeamID]
-			st.D++
-			teamStats[m.HomeTeamID] = st
-			st = teamStats[m.AwayTeamID]
-			st.D++
-			teamStats[m.AwayTeamID] = st
-		}
+		PointsDeducted int    `json:"PointsDeducted"`
 	}
 	var standings []StandingsEntry
 	for _, entry := range table {
-		st := teamStats[entry.TeamID]
 		standings = append(standings, StandingsEntry{
 			TeamID: entry.TeamID,
 			TeamName: entry.TeamName,
@@ -274,9 +238,10 @@ func getLeagueTable(w http.ResponseWriter, r *http.Request) {
 			GoalsAgainst: entry.GoalsAgainst,
 			GoalDifference: entry.GoalDifference,
 			MatchesPlayed: entry.MatchesPlayed,
-			Wins: st.W,
-			Draws: st.D,
-			Losses: st.L,
+			Wins: entry.Wins,
+			Draws: entry.Draws,
+			Losses: entry.Losses,
+			PointsDeducted: entry.PointsDeducted,
 		})
 	}
 	// Get latest week and match results
@@ -289,35 +254,43 @@ func getLeagueTable(w http.ResponseWriter, r *http.Request) {
 }
 
 func playNextWeek(w http.ResponseWriter, r *http.Request) {
-	league, err := leagueRepo.GetLeague()
+	sim, err := simulatorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	week := 1
-	for _, m := range league.Matches {
-		if m.Week > week && !m.Played {
-			week = m.Week
-		}
+	avail, err := models.LoadAvailability(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
 	}
+	week := nextUnplayedWeek(league.Matches)
+	sim = models.BindSimulator(sim, league)
 	for i := range league.Matches {
 		m := &league.Matches[i]
-		if m.Week == week && !m.Played {
-			home, away := getTeamByID(league.Teams, m.HomeTeamID), getTeamByID(league.Teams, m.AwayTeamID)
-			hg, ag := matchSim.SimulateMatch(home, away)
-			m.HomeGoals = sql.NullInt64{Int64: int64(hg), Valid: true}
-			m.AwayGoals = sql.NullInt64{Int64: int64(ag), Valid: true}
-			m.Played = true
-			repo := models.SQLiteMatchRepository{}
-			repo.UpdateMatch(*m)
+		if m.Week == week && m.Status == models.StatusScheduled {
+			playFixture(league, avail, sim, m)
 		}
 	}
+	if err := rateLeague(&league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if err := leagueRepo.UpdateLeague(league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
 	table := league.CalculateTable()
 	teamNames := make(map[int]string)
 	for _, t := range league.Teams {
 		teamNames[t.ID] = t.Name
 	}
-	standings := buildStandings(table, league.Matches)
+	standings := buildStandings(table)
 	matchResults := getMatchResultsForWeek(league.Matches, teamNames, week)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"standings": standings,
@@ -325,30 +298,69 @@ func playNextWeek(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// playAllPending reports whether play-all should play a match: anything
+// without a counting result, postponed and abandoned matches included,
+// except a match that is live right now
+func playAllPending(m models.Match) bool {
+	return !m.Status.CountsInTable() && m.Status != models.StatusLive
+}
+
 func playAll(w http.ResponseWriter, r *http.Request) {
-	league, err := leagueRepo.GetLeague()
+	sim, err := simulatorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	for i := range league.Matches {
-		m := &league.Matches[i]
-		if !m.Played {
-			home, away := getTeamByID(league.Teams, m.HomeTeamID), getTeamByID(league.Teams, m.AwayTeamID)
-			hg, ag := matchSim.SimulateMatch(home, away)
-			m.HomeGoals = sql.NullInt64{Int64: int64(hg), Valid: true}
-			m.AwayGoals = sql.NullInt64{Int64: int64(ag), Valid: true}
-			m.Played = true
-			repo := models.SQLiteMatchRepository{}
-			repo.UpdateMatch(*m)
+	// Play week by week so each round is simulated with ratings that
+	// reflect the results before it
+	weeks := []int{}
+	seenWeeks := make(map[int]bool)
+	for _, m := range league.Matches {
+		if playAllPending(m) && !seenWeeks[m.Week] {
+			seenWeeks[m.Week] = true
+			weeks = append(weeks, m.Week)
 		}
 	}
+	sort.Ints(weeks)
+	start, err := models.SQLiteRatingRepository{}.GetStartRatings(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	avail, err := models.LoadAvailability(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	// Bind once so a fitted model such as dixon-coles rates the teams on
+	// the results actually played, not on ones simulated earlier in this
+	// run. Rebinding the bound simulator each week only updates form.
+	sim = models.BindSimulator(sim, league)
+	for _, week := range weeks {
+		weekSim := models.BindSimulator(sim, league)
+		for i := range league.Matches {
+			m := &league.Matches[i]
+			if m.Week == week && playAllPending(*m) {
+				playFixture(league, avail, weekSim, m)
+			}
+		}
+		league.RatingHistory = rateTeams(&league, start)
+	}
+	if err := leagueRepo.UpdateLeague(league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
 	table := league.CalculateTable()
 	teamNames := make(map[int]string)
 	for _, t := range league.Teams {
 		teamNames[t.ID] = t.Name
 	}
-	standings := buildStandings(table, league.Matches)
+	standings := buildStandings(table)
 	latestWeek := getLatestPlayedWeek(league.Matches)
 	matchResults := getMatchResultsForWeek(league.Matches, teamNames, latestWeek)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -369,14 +381,39 @@ func editMatchResult(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		HomeGoals int `json:"home_goals"`
-		AwayGoals int `json:"away_goals"`
+		HomeGoals int              `json:"home_goals"`
+		AwayGoals int              `json:"away_goals"`
+		Events    []MatchEventJSON `json:"events"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", 400)
 		return
 	}
-	league, err := leagueRepo.GetLeague()
+	if req.HomeGoals < 0 || req.AwayGoals < 0 {
+		http.Error(w, "Goals cannot be negative", 400)
+		return
+	}
+	// The match decides which league's table is affected
+	matchRepo := models.SQLiteMatchRepository{}
+	match, err := matchRepo.GetMatchByID(id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Match not found", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	season, err := models.SQLiteSeasonRepository{}.GetSeason(match.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if season.Closed {
+		http.Error(w, "Match belongs to a closed season", http.StatusConflict)
+		return
+	}
+	league, err := leagueRepo.GetLeague(season.LeagueID)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -384,11 +421,22 @@ func editMatchResult(w http.ResponseWriter, r *http.Request) {
 	found := false
 	for i := range league.Matches {
 		if league.Matches[i].ID == id {
-			league.Matches[i].HomeGoals = sql.NullInt64{Int64: int64(req.HomeGoals), Valid: true}
-			league.Matches[i].AwayGoals = sql.NullInt64{Int64: int64(req.AwayGoals), Valid: true}
-			league.Matches[i].Played = true
-			repo := models.SQLiteMatchRepository{}
-			repo.UpdateMatch(league.Matches[i])
+			m := &league.Matches[i]
+			m.SetResult(req.HomeGoals, req.AwayGoals)
+			// Without events the old timeline no longer matches the score,
+			// so it is cleared; events sent with the result must match it
+			m.Events = eventsFromJSON(m.ID, req.Events)
+			if req.Events != nil {
+				if err := models.ValidateEvents(*m, m.Events); err != nil {
+					http.Error(w, err.Error(), 400)
+					return
+				}
+				home, away := getTeamByID(league.Teams, m.HomeTeamID), getTeamByID(league.Teams, m.AwayTeamID)
+				if err := models.AttachPlayers(m.Events, home, away); err != nil {
+					http.Error(w, err.Error(), 400)
+					return
+				}
+			}
 			found = true
 			break
 		}
@@ -397,33 +445,37 @@ func editMatchResult(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Match not found", 404)
 		return
 	}
+	if err := rateLeague(&league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if err := leagueRepo.UpdateLeague(league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
 	table := league.CalculateTable()
 	json.NewEncoder(w).Encode(table)
 }
 
+// estimateFinalTable plays out the rest of the season once, from the
+// current state or from the table after ?from_week=
 func estimateFinalTable(w http.ResponseWriter, r *http.Request) {
-	league, err := leagueRepo.GetLeague()
+	mc, err := monteCarloFromRequest(r, 1)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), 400)
 		return
 	}
-	copyLeague := league
-	for i := range copyLeague.Matches {
-		m := &copyLeague.Matches[i]
-		if !m.Played && m.Week > 4 {
-			home, away := getTeamByID(copyLeague.Teams, m.HomeTeamID), getTeamByID(copyLeague.Teams, m.AwayTeamID)
-			hg, ag := matchSim.SimulateMatch(home, away)
-			m.HomeGoals = sql.NullInt64{Int64: int64(hg), Valid: true}
-			m.AwayGoals = sql.NullInt64{Int64: int64(ag), Valid: true}
-			m.Played = true
-		}
+	league, _, ok := estimateLeague(w, r, -1)
+	if !ok {
+		return
 	}
+	copyLeague := mc.SimulateOnce(league, 0)
 	table := copyLeague.CalculateTable()
 	teamNames := make(map[int]string)
 	for _, t := range league.Teams {
 		teamNames[t.ID] = t.Name
 	}
-	standings := buildStandings(table, league.Matches)
+	standings := buildStandings(table)
 	latestWeek := getLatestPlayedWeek(league.Matches)
 	matchResults := getMatchResultsForWeek(league.Matches, teamNames, latestWeek)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -433,7 +485,7 @@ func estimateFinalTable(w http.ResponseWriter, r *http.Request) {
 }
 
 func resultsByWeek(w http.ResponseWriter, r *http.Request) {
-	league, err := leagueRepo.GetLeague()
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -457,122 +509,189 @@ func resultsByWeek(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// afterWeek4Estimate estimates the final table from the results up to
+// week 4, or up to ?from_week=
 func afterWeek4Estimate(w http.ResponseWriter, r *http.Request) {
-	league, err := leagueRepo.GetLeague()
+	mc, err := monteCarloFromRequest(r, 1)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	league, fromWeek, ok := estimateLeague(w, r, 4)
+	if !ok {
 		return
 	}
 	played := []MatchJSON{}
 	for _, m := range league.Matches {
-		if m.Week <= 4 && m.Played {
+		if m.Played {
 			played = append(played, matchToJSON(m))
 		}
 	}
-	copyLeague := league
-	for i := range copyLeague.Matches {
-		m := &copyLeague.Matches[i]
-		if !m.Played && m.Week > 4 {
-			home, away := getTeamByID(copyLeague.Teams, m.HomeTeamID), getTeamByID(copyLeague.Teams, m.AwayTeamID)
-			hg, ag := matchSim.SimulateMatch(home, away)
-			m.HomeGoals = sql.NullInt64{Int64: int64(hg), Valid: true}
-			m.AwayGoals = sql.NullInt64{Int64: int64(ag), Valid: true}
-			m.Played = true
-		}
-	}
+	copyLeague := mc.SimulateOnce(league, 0)
 	table := copyLeague.CalculateTable()
 	teamNames := make(map[int]string)
 	for _, t := range league.Teams {
 		teamNames[t.ID] = t.Name
 	}
-	standings := buildStandings(table, league.Matches)
+	standings := buildStandings(table)
 	latestWeek := getLatestPlayedWeek(league.Matches)
 	matchResults := getMatchResultsForWeek(league.Matches, teamNames, latestWeek)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"played_matches_up_to_week4": played,
+		"from_week": fromWeek,
+		"played_matches_up_to_week": played,
 		"estimated_final_table": standings,
 		"match_results": matchResults,
 	})
 }
 
-// Deep copy helper for League
-func deepCopyLeague(league models.League) models.League {
-	newLeague := league
-	newLeague.Teams = make([]models.Team, len(league.Teams))
-	copy(newLeague.Teams, league.Teams)
-	newLeague.Matches = make([]models.Match, len(league.Matches))
-	for i, m := range league.Matches {
-		newLeague.Matches[i] = m
-	}
-	return newLeague
+// championEstimation returns each team's chance of the title in percent,
+// over ?simulations= runs (default 1000) seeded by ?seed=, from the current
+// state or from the table after ?from_week=
+func championEstimation(w http.ResponseWriter, r *http.Request) {
+	mc, err := monteCarloFromRequest(r, defaultSimulations)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	league, _, ok := estimateLeague(w, r, -1)
+	if !ok {
+		return
+	}
+	forecast := mc.Run(league)
+	result := make(map[string]float64)
+	for _, tf := range forecast.Summary(nil) {
+		if tf.Positions[0] > 0 {
+			result[tf.TeamName] = tf.Positions[0]
+		}
+	}
+	json.NewEncoder(w).Encode(result)
 }
 
-func championEstimation(w http.ResponseWriter, r *http.Request) {
-	const simulations = 1000
-	league, err := leagueRepo.GetLeague()
+func resetLeague(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	teamChampions := make(map[int]int)
-	teamNames := make(map[int]string)
-	for _, t := range league.Teams {
-		teamNames[t.ID] = t.Name
+	// Only the season in progress is reset; closed seasons stay archived
+	for i := range league.Matches {
+		league.Matches[i].SetStatus(models.StatusScheduled)
 	}
-	for sim := 0; sim < simulations; sim++ {
-		copyLeague := deepCopyLeague(league)
-		r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(sim*1000)))
-		for i := range copyLeague.Matches {
-			m := &copyLeague.Matches[i]
-			if !m.Played && m.Week > 4 {
-				home, away := getTeamByID(copyLeague.Teams, m.HomeTeamID), getTeamByID(copyLeague.Teams, m.AwayTeamID)
-				hg, ag := simulateWithRand(home, away, r)
-				m.HomeGoals = sql.NullInt64{Int64: int64(hg), Valid: true}
-				m.AwayGoals = sql.NullInt64{Int64: int64(ag), Valid: true}
-				m.Played = true
-			}
-		}
-		table := copyLeague.CalculateTable()
-		if len(table) > 0 {
-			teamChampions[table[0].TeamID]++
-		}
+	if err := rateLeague(&league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
 	}
-	result := make(map[string]float64)
-	for id, count := range teamChampions {
-		result[teamNames[id]] = float64(count) * 100.0 / float64(simulations)
+	if err := leagueRepo.UpdateLeague(league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
 	}
-	json.NewEncoder(w).Encode(result)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "reset successful"})
 }
 
-// simulateWithRand is like SimulateMatch but uses a custom rand.Rand
-func simulateWithRand(home models.Team, away models.Team, r *rand.Rand) (int, int) {
-	homeStrength := float64(home.Strength) * 1.1
-	awayStrength := float64(away.Strength)
-	totalStrength := homeStrength + awayStrength
-	homeGoals := int((homeStrength/totalStrength)*3 + 0.5)
-	awayGoals := int((awayStrength/totalStrength)*3 + 0.5)
-	if homeGoals > 0 && r.Intn(4) == 0 { homeGoals-- }
-	if awayGoals > 0 && r.Intn(4) == 0 { awayGoals-- }
-	if r.Intn(10) == 0 { homeGoals++ }
-	if r.Intn(10) == 0 { awayGoals++ }
-	if homeGoals < 0 { homeGoals = 0 }
-	if awayGoals < 0 { awayGoals = 0 }
-	return homeGoals, awayGoals
+// createFixtures replaces a season's schedule with a double round-robin for
+// the league's current teams
+func createFixtures(leagueID int, seasonID int) error {
+	teamRepo := models.SQLiteTeamRepository{}
+	teams, err := teamRepo.GetTeamsByLeague(leagueID)
+	if err != nil {
+		return err
+	}
+	return models.SQLiteMatchRepository{}.ReplaceSeasonMatches(seasonID, models.GenerateDoubleRoundRobin(teams))
 }
 
-func resetLeague(w http.ResponseWriter, r *http.Request) {
+func generateFixtures(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	db := storage.GetDB()
-	_, err := db.Exec("UPDATE matches SET home_goals = NULL, away_goals = NULL, played = 0")
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	started, err := seasonStarted(league)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
+	if started {
+		http.Error(w, "Season has started; reset it before regenerating fixtures", http.StatusConflict)
+		return
+	}
+	if err := createFixtures(league.ID, league.SeasonID); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	league, err = leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if err := rebuildStandings(&league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	fixtures := []MatchJSON{}
+	for _, m := range league.Matches {
+		fixtures = append(fixtures, matchToJSON(m))
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "reset successful"})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"fixtures": fixtures,
+	})
+}
+
+func teamRatings(w http.ResponseWriter, r *http.Request) {
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	repo := models.SQLiteRatingRepository{}
+	history, err := repo.GetRatingHistory(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	type RatingPointJSON struct {
+		Week   int     `json:"week"`
+		Rating float64 `json:"rating"`
+	}
+	type TeamRatingJSON struct {
+		TeamID        int               `json:"team_id"`
+		TeamName      string            `json:"team_name"`
+		Strength      int               `json:"strength"`
+		CurrentRating float64           `json:"current_rating"`
+		History       []RatingPointJSON `json:"history"`
+	}
+	byTeam := make(map[int][]RatingPointJSON)
+	for _, p := range history {
+		byTeam[p.TeamID] = append(byTeam[p.TeamID], RatingPointJSON{Week: p.Week, Rating: p.Rating})
+	}
+	result := []TeamRatingJSON{}
+	for _, t := range league.Teams {
+		current := t.Rating
+		if current == 0 {
+			current = models.InitialRating(t.Strength)
+		}
+		result = append(result, TeamRatingJSON{
+			TeamID:        t.ID,
+			TeamName:      t.Name,
+			Strength:      t.Strength,
+			CurrentRating: current,
+			History:       byTeam[t.ID],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CurrentRating > result[j].CurrentRating
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
 }
 
 func getTeamByID(teams []models.Team, id int) models.Team {
@@ -585,6 +704,16 @@ func getTeamByID(teams []models.Team, id int) models.Team {
 }
 
 func main() {
+	simulatorName := flag.String("simulator", "basic", "match simulator to use: "+strings.Join(simulatorNames(), ", "))
+	paramFlags := simulatorParamFlags(flag.CommandLine)
+	flag.Parse()
+	simulatorConfig = models.SimulatorConfig{Model: *simulatorName, Params: setSimulatorParams(flag.CommandLine, paramFlags)}
+	sim, values, err := simulators.Build(simulatorConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	matchSim = sim
+	formWindow = int(values["form_window"])
 	rand.Seed(time.Now().UnixNano())
 	initDBAndData()
 	http.HandleFunc("/league/table", getLeagueTable)
@@ -595,7 +724,32 @@ func main() {
 	http.HandleFunc("/league/after-week4-estimate", afterWeek4Estimate)
 	http.HandleFunc("/league/champion-estimation", championEstimation)
 	http.HandleFunc("/league/reset", resetLeague)
-	http.HandleFunc("/match/", editMatchResult)
+	http.HandleFunc("/league/generate-fixtures", generateFixtures)
+	http.HandleFunc("/league/ratings", teamRatings)
+	http.HandleFunc("/league/close-season", closeSeason)
+	http.HandleFunc("/league/tie-breakers", tieBreakers)
+	http.HandleFunc("/league/scoring-rules", scoringRules)
+	http.HandleFunc("/league/deductions", pointDeductions)
+	http.HandleFunc("/league/standings-check", standingsCheck)
+	http.HandleFunc("/league/position-probabilities", positionProbabilities)
+	http.HandleFunc("/league/scenario", whatIfScenario)
+	http.HandleFunc("/league/replay-postponed", replayPostponed)
+	http.HandleFunc("/league/event-stats", eventStats)
+	http.HandleFunc("/league/leaderboards", leaderboards)
+	http.HandleFunc("/league/leaderboards/history", leaderboardHistory)
+	http.HandleFunc("/league/availability", availability)
+	http.HandleFunc("/league/dixon-coles", dixonColesFit)
+	http.HandleFunc("/simulators", listSimulators)
+	http.HandleFunc("/leagues", leaguesHandler)
+	http.HandleFunc("/leagues/", leagueRoutes)
+	http.HandleFunc("/seasons", listSeasons)
+	http.HandleFunc("/seasons/", seasonArchive)
+	http.HandleFunc("/match/", matchDetail)
+	http.HandleFunc("/matches", matchesHandler)
+	http.HandleFunc("/matches/", matchRoutes)
+	http.HandleFunc("/teams", teamsHandler)
+	http.HandleFunc("/teams/", teamRoutes)
+	http.HandleFunc("/players/", playerRoutes)
 	log.Println("Server started at :8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 } 
\ No newline at end of file
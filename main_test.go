@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"Case_study/models"
+	"Case_study/storage"
+)
+
+// newTestLeague opens a fresh database with the default four-team league
+func newTestLeague(t *testing.T) {
+	t.Helper()
+	storage.InitDB(filepath.Join(t.TempDir(), "league.db"), "sql/schema.sql")
+	t.Cleanup(func() { storage.GetDB().Close() })
+	teams := []models.Team{
+		{ID: 1, Name: "Lions", Strength: 90},
+		{ID: 2, Name: "Tigers", Strength: 80},
+		{ID: 3, Name: "Bears", Strength: 70},
+		{ID: 4, Name: "Wolves", Strength: 60},
+	}
+	if _, err := setupLeague("Default League", teams); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func putResult(t *testing.T, id string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	matchDetail(rec, httptest.NewRequest(http.MethodPut, "/match/"+id, strings.NewReader(body)))
+	return rec
+}
+
+func TestEditMatchResultWithoutEvents(t *testing.T) {
+	newTestLeague(t)
+	match, err := models.SQLiteMatchRepository{}.GetMatchByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goal := `{"home_goals":1,"away_goals":0,"events":[{"minute":10,"type":"goal","team_id":` + strconv.Itoa(match.HomeTeamID) + `,"player":"Scorer"}]}`
+	if rec := putResult(t, "1", goal); rec.Code != http.StatusOK {
+		t.Fatalf("result with events: %d %s", rec.Code, rec.Body)
+	}
+	if rec := putResult(t, "1", `{"home_goals":2,"away_goals":2}`); rec.Code != http.StatusOK {
+		t.Fatalf("score-only result: %d %s", rec.Code, rec.Body)
+	}
+	rec := httptest.NewRecorder()
+	matchDetail(rec, httptest.NewRequest(http.MethodGet, "/match/1", nil))
+	var detail struct {
+		Match    MatchJSON        `json:"match"`
+		Timeline []MatchEventJSON `json:"timeline"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&detail); err != nil {
+		t.Fatal(err)
+	}
+	if detail.Match.HomeGoals == nil || *detail.Match.HomeGoals != 2 || *detail.Match.AwayGoals != 2 {
+		t.Errorf("score not stored: %+v", detail.Match)
+	}
+	if len(detail.Timeline) != 0 {
+		t.Errorf("old timeline kept: %+v", detail.Timeline)
+	}
+}
+
+func TestEditMatchResultRejectsMismatchedEvents(t *testing.T) {
+	newTestLeague(t)
+	if rec := putResult(t, "1", `{"home_goals":1,"away_goals":0,"events":[]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for events that do not match the score, got %d", rec.Code)
+	}
+}
+
+func TestUpdateTeamKeepsFixtures(t *testing.T) {
+	newTestLeague(t)
+	matchRepo := models.SQLiteMatchRepository{}
+	before, err := matchRepo.GetMatchesBySeason(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	teamRoutes(rec, httptest.NewRequest(http.MethodPatch, "/teams/1", strings.NewReader(`{"name":"Lionesses","strength":85}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rename: %d %s", rec.Code, rec.Body)
+	}
+	after, err := matchRepo.GetMatchesBySeason(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("fixtures changed from %d to %d matches", len(before), len(after))
+	}
+	for i := range before {
+		if before[i].ID != after[i].ID || before[i].HomeTeamID != after[i].HomeTeamID || before[i].Week != after[i].Week {
+			t.Errorf("fixture %d regenerated: %+v became %+v", i, before[i], after[i])
+		}
+	}
+}
+
+func TestPostponedMatchCountsAsStarted(t *testing.T) {
+	newTestLeague(t)
+	league, err := leagueRepo.GetLeague(defaultLeagueID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started, err := seasonStarted(league); err != nil || started {
+		t.Fatalf("fresh season started = %v, %v", started, err)
+	}
+	league.Matches[0].Status = models.StatusPostponed
+	if started, err := seasonStarted(league); err != nil || !started {
+		t.Fatalf("season with a postponed match started = %v, %v", started, err)
+	}
+}
+
+func TestDeleteTeamWithStartedMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		start func(t *testing.T, m models.Match)
+	}{
+		{"postponed", func(t *testing.T, m models.Match) {
+			m.SetStatus(models.StatusPostponed)
+			if err := (models.SQLiteMatchRepository{}).UpdateMatch(m); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"events only", func(t *testing.T, m models.Match) {
+			if _, err := storage.GetDB().Exec("INSERT INTO match_events (match_id, minute, type, team_id, player, weeks, extra) VALUES (?, 10, 'yellow_card', ?, 'Someone', 0, '')", m.ID, m.HomeTeamID); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestLeague(t)
+			m, err := models.SQLiteMatchRepository{}.GetMatchByID(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.start(t, m)
+			rec := httptest.NewRecorder()
+			teamRoutes(rec, httptest.NewRequest(http.MethodDelete, "/teams/"+strconv.Itoa(m.HomeTeamID), nil))
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+			}
+			if _, err := (models.SQLiteMatchRepository{}).GetMatchByID(m.ID); err != nil {
+				t.Errorf("started match gone after a refused delete: %v", err)
+			}
+		})
+	}
+}
+
+// startLiveMatch puts the first fixture live and returns it
+func startLiveMatch(t *testing.T) models.Match {
+	t.Helper()
+	matchRepo := models.SQLiteMatchRepository{}
+	m, err := matchRepo.GetMatchByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetStatus(models.StatusLive)
+	if err := matchRepo.UpdateMatch(m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestUpdateLeagueLeavesLiveScore(t *testing.T) {
+	newTestLeague(t)
+	m := startLiveMatch(t)
+	stale, err := leagueRepo.GetLeague(defaultLeagueID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetLiveScore(1, 0)
+	if err := (models.SQLiteMatchRepository{}).UpdateMatch(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := leagueRepo.UpdateLeague(stale); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := models.SQLiteMatchRepository{}.GetMatchByID(m.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.StatusLive || stored.HomeGoals.Int64 != 1 {
+		t.Errorf("live score overwritten: %+v", stored)
+	}
+}
+
+func TestFinishLiveMatchKeepsLaterWeeks(t *testing.T) {
+	newTestLeague(t)
+	m := startLiveMatch(t)
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		playNextWeek(rec, httptest.NewRequest(http.MethodGet, "/league/next-week", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("next week: %d %s", rec.Code, rec.Body)
+		}
+	}
+	m.SetResult(2, 1)
+	if err := finishLiveMatch(defaultLeagueID, m); err != nil {
+		t.Fatal(err)
+	}
+	league, err := leagueRepo.GetLeague(defaultLeagueID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	played := 0
+	for _, lm := range league.Matches {
+		if lm.Played {
+			played++
+		}
+	}
+	// The live match, the other week 1 fixture and both of week 2
+	if played != 4 {
+		t.Errorf("%d matches played, want 4", played)
+	}
+	history, err := models.SQLiteRatingRepository{}.GetRatingHistory(league.SeasonID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := 0
+	for _, p := range history {
+		if p.Week > latest {
+			latest = p.Week
+		}
+	}
+	if latest < 2 {
+		t.Errorf("rating history stops at week %d after week 2 was played", latest)
+	}
+}
+
+func TestReplaceSeasonMatchesKeepsScheduleOnFailure(t *testing.T) {
+	newTestLeague(t)
+	before, err := leagueRepo.GetLeague(defaultLeagueID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second fixture reuses the first one's ID, so its insert fails
+	clash := []models.Match{{ID: 100, HomeTeamID: 1, AwayTeamID: 2, Week: 1}, {ID: 100, HomeTeamID: 3, AwayTeamID: 4, Week: 1}}
+	if err := (models.SQLiteMatchRepository{}).ReplaceSeasonMatches(before.SeasonID, clash); err == nil {
+		t.Fatal("duplicate match IDs accepted")
+	}
+	after, err := leagueRepo.GetLeague(defaultLeagueID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after.Matches) != len(before.Matches) {
+		t.Errorf("%d fixtures after a failed replace, want %d", len(after.Matches), len(before.Matches))
+	}
+}
+
+func TestCloseSeasonRollsBackOnFailure(t *testing.T) {
+	newTestLeague(t)
+	playAll(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/league/play-all", nil))
+	league, err := leagueRepo.GetLeague(defaultLeagueID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fail the last step, writing the new season's starting ratings
+	trigger := `CREATE TRIGGER fail_new_season BEFORE INSERT ON team_ratings WHEN NEW.season_id != ` + strconv.Itoa(league.SeasonID) + `
+		BEGIN SELECT RAISE(ABORT, 'ratings unavailable'); END`
+	if _, err := storage.GetDB().Exec(trigger); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	closeSeason(rec, httptest.NewRequest(http.MethodPost, "/league/close-season", nil))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "ratings unavailable") {
+		t.Fatalf("close-season with failing ratings: %d %s", rec.Code, rec.Body)
+	}
+	seasonRepo := models.SQLiteSeasonRepository{}
+	seasons, err := seasonRepo.GetSeasonsByLeague(defaultLeagueID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seasons) != 1 || seasons[0].ID != league.SeasonID || seasons[0].Closed {
+		t.Errorf("seasons after a failed close: %+v", seasons)
+	}
+	matches, err := models.SQLiteMatchRepository{}.GetMatchesBySeason(league.SeasonID + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("failed close left %d fixtures for a new season", len(matches))
+	}
+}
+
+func TestSetupLeagueLeavesNothingOnFailure(t *testing.T) {
+	newTestLeague(t)
+	// The second team reuses the first one's ID, so its insert fails
+	teams := []models.Team{{ID: 50, Name: "Hawks", Strength: 70}, {ID: 50, Name: "Owls", Strength: 60}}
+	if _, err := setupLeague("Broken League", teams); err == nil {
+		t.Fatal("duplicate team IDs accepted")
+	}
+	leagues, err := leagueRepo.GetAllLeagues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leagues) != 1 {
+		t.Errorf("%d leagues after a failed setup, want 1", len(leagues))
+	}
+	var orphans int
+	if err := storage.GetDB().QueryRow("SELECT COUNT(*) FROM teams WHERE id = 50").Scan(&orphans); err != nil {
+		t.Fatal(err)
+	}
+	if orphans != 0 {
+		t.Errorf("failed setup left %d teams behind", orphans)
+	}
+}
+
+func TestGenerateFixturesRefusedOnceStarted(t *testing.T) {
+	newTestLeague(t)
+	m := startLiveMatch(t)
+	rec := httptest.NewRecorder()
+	generateFixtures(rec, httptest.NewRequest(http.MethodPost, "/league/generate-fixtures", nil))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("regenerate with a live match: %d %s", rec.Code, rec.Body)
+	}
+	if _, err := (models.SQLiteMatchRepository{}).GetMatchByID(m.ID); err != nil {
+		t.Errorf("live match gone after a refused regenerate: %v", err)
+	}
+}
+
+func TestAfterWeekEstimateNamesPlayedMatches(t *testing.T) {
+	newTestLeague(t)
+	playAll(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/league/play-all", nil))
+	rec := httptest.NewRecorder()
+	afterWeek4Estimate(rec, httptest.NewRequest(http.MethodGet, "/league/after-week4-estimate?from_week=2&seed=1", nil))
+	var body struct {
+		FromWeek int         `json:"from_week"`
+		Played   []MatchJSON `json:"played_matches_up_to_week"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	// Two matches a week in a four-team league
+	if body.FromWeek != 2 || len(body.Played) != 4 {
+		t.Errorf("from_week %d with %d played matches, want 2 with 4", body.FromWeek, len(body.Played))
+	}
+}
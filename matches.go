@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Case_study/models"
+)
+
+// matchesHandler serves /matches: GET lists fixtures and POST adds one
+func matchesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listMatches(w, r)
+	case http.MethodPost:
+		createMatch(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listMatches returns the fixtures of a league's current season, or of
+// ?season_id=, optionally narrowed with ?week= and ?team_id=
+func listMatches(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	leagueID := defaultLeagueID
+	if v := q.Get("league_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid league ID", 400)
+			return
+		}
+		leagueID = id
+	}
+	week, teamID := 0, 0
+	if v := q.Get("week"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid week", 400)
+			return
+		}
+		week = n
+	}
+	if v := q.Get("team_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid team ID", 400)
+			return
+		}
+		teamID = n
+	}
+	var matches []models.Match
+	if v := q.Get("season_id"); v != "" {
+		seasonID, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid season ID", 400)
+			return
+		}
+		if _, err := (models.SQLiteSeasonRepository{}).GetSeason(seasonID); errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "Season not found", 404)
+			return
+		}
+		matches, err = models.SQLiteMatchRepository{}.GetMatchesBySeason(seasonID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+	} else {
+		league, err := leagueRepo.GetLeague(leagueID)
+		if errors.Is(err, models.ErrNotFound) {
+			http.Error(w, "League not found", 404)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		matches = league.Matches
+	}
+	result := []MatchJSON{}
+	for _, m := range matches {
+		if week != 0 && m.Week != week {
+			continue
+		}
+		if teamID != 0 && m.HomeTeamID != teamID && m.AwayTeamID != teamID {
+			continue
+		}
+		result = append(result, matchToJSON(m))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// createMatch adds an unplayed fixture to the current season of the teams'
+// league
+func createMatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		HomeTeamID int `json:"home_team_id"`
+		AwayTeamID int `json:"away_team_id"`
+		Week       int `json:"week"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	if req.Week < 1 {
+		http.Error(w, "Week must be at least 1", 400)
+		return
+	}
+	if req.HomeTeamID == req.AwayTeamID {
+		http.Error(w, "A team cannot play itself", 400)
+		return
+	}
+	teamRepo := models.SQLiteTeamRepository{}
+	home, err := teamRepo.GetTeamByID(req.HomeTeamID)
+	if err != nil {
+		http.Error(w, "Unknown home team", 400)
+		return
+	}
+	away, err := teamRepo.GetTeamByID(req.AwayTeamID)
+	if err != nil {
+		http.Error(w, "Unknown away team", 400)
+		return
+	}
+	if home.LeagueID != away.LeagueID {
+		http.Error(w, "Teams play in different leagues", 400)
+		return
+	}
+	league, err := leagueRepo.GetLeague(home.LeagueID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	m := models.Match{SeasonID: league.SeasonID, HomeTeamID: home.ID, AwayTeamID: away.ID, Week: req.Week, Status: models.StatusScheduled}
+	if clash, ok := models.FixtureClash(league.Matches, m); ok {
+		http.Error(w, "Clashes with match "+strconv.Itoa(clash.ID)+" in week "+strconv.Itoa(m.Week), http.StatusConflict)
+		return
+	}
+	m.ID, err = models.SQLiteMatchRepository{}.CreateMatch(m)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(matchToJSON(m))
+}
+
+// matchRoutes serves /matches/{id}: GET, PATCH to reschedule or swap home
+// and away, and DELETE. POST /matches/{id}/revert undoes a result,
+// /matches/{id}/status moves the match through its lifecycle and
+// /matches/{id}/replay plays a postponed or abandoned match.
+// GET /matches/{id}/live plays the match live as an event stream and GET
+// /matches/{id}/prediction returns its outcome probabilities.
+func matchRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/matches/"):], "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid match ID", 400)
+		return
+	}
+	match, err := models.SQLiteMatchRepository{}.GetMatchByID(id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Match not found", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if len(parts) == 2 && (parts[1] == "live" || parts[1] == "prediction") {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if parts[1] == "live" {
+			liveMatch(w, r, match)
+		} else {
+			matchPrediction(w, r, match)
+		}
+		return
+	}
+	if len(parts) == 2 {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		switch parts[1] {
+		case "revert":
+			revertMatch(w, match)
+		case "status":
+			changeMatchStatus(w, r, match)
+		case "replay":
+			replayMatch(w, match)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+	if len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(matchToJSON(match))
+	case http.MethodPatch:
+		rescheduleMatch(w, r, match)
+	case http.MethodDelete:
+		deleteMatch(w, match)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// openMatchLeague loads the league a match belongs to, refusing matches of
+// closed seasons. It returns the match's index in league.Matches.
+func openMatchLeague(w http.ResponseWriter, match models.Match) (models.League, int, bool) {
+	season, err := models.SQLiteSeasonRepository{}.GetSeason(match.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return models.League{}, 0, false
+	}
+	if season.Closed {
+		http.Error(w, "Match belongs to a closed season", http.StatusConflict)
+		return models.League{}, 0, false
+	}
+	league, err := leagueRepo.GetLeague(season.LeagueID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return models.League{}, 0, false
+	}
+	for i, m := range league.Matches {
+		if m.ID == match.ID {
+			return league, i, true
+		}
+	}
+	http.Error(w, "Match not found", 404)
+	return models.League{}, 0, false
+}
+
+func rescheduleMatch(w http.ResponseWriter, r *http.Request, match models.Match) {
+	var req struct {
+		Week         *int `json:"week"`
+		SwapHomeAway bool `json:"swap_home_away"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	if match.Played {
+		http.Error(w, "Revert the result before rescheduling a played match", http.StatusConflict)
+		return
+	}
+	if match.Status == models.StatusLive {
+		http.Error(w, "A live match cannot be rescheduled", http.StatusConflict)
+		return
+	}
+	league, i, ok := openMatchLeague(w, match)
+	if !ok {
+		return
+	}
+	m := &league.Matches[i]
+	if req.Week != nil {
+		if *req.Week < 1 {
+			http.Error(w, "Week must be at least 1", 400)
+			return
+		}
+		m.Week = *req.Week
+		// A postponed or abandoned match given a new date is back on the
+		// schedule
+		m.SetStatus(models.StatusScheduled)
+	}
+	if req.SwapHomeAway {
+		m.HomeTeamID, m.AwayTeamID = m.AwayTeamID, m.HomeTeamID
+	}
+	if clash, found := models.FixtureClash(league.Matches, *m); found {
+		http.Error(w, "Clashes with match "+strconv.Itoa(clash.ID)+" in week "+strconv.Itoa(m.Week), http.StatusConflict)
+		return
+	}
+	if !saveLeague(w, league) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matchToJSON(*m))
+}
+
+func deleteMatch(w http.ResponseWriter, match models.Match) {
+	if match.Played {
+		http.Error(w, "Revert the result before deleting a played match", http.StatusConflict)
+		return
+	}
+	if match.Status == models.StatusLive {
+		http.Error(w, "A live match cannot be deleted", http.StatusConflict)
+		return
+	}
+	if _, _, ok := openMatchLeague(w, match); !ok {
+		return
+	}
+	if err := (models.SQLiteMatchRepository{}).DeleteMatch(match.ID); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revertMatch clears a result so the match can be played again, and
+// replays standings and ratings without it
+func revertMatch(w http.ResponseWriter, match models.Match) {
+	league, i, ok := openMatchLeague(w, match)
+	if !ok {
+		return
+	}
+	m := &league.Matches[i]
+	m.SetStatus(models.StatusScheduled)
+	if err := rateLeague(&league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if !saveLeague(w, league) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"match":     matchToJSON(*m),
+		"standings": buildStandings(league.CalculateTable()),
+	})
+}
+
+// saveLeague stores the league, answering 409 for a status change the
+// repository refuses and 500 for any other failure
+func saveLeague(w http.ResponseWriter, league models.League) bool {
+	err := leagueRepo.UpdateLeague(league)
+	if errors.Is(err, models.ErrInvalidTransition) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return false
+	}
+	return true
+}
+
+// changeMatchStatus moves a match to a new status. Completed and awarded
+// need a score; an abandoned match may record the score it was stopped at.
+func changeMatchStatus(w http.ResponseWriter, r *http.Request, match models.Match) {
+	var req struct {
+		Status    models.MatchStatus `json:"status"`
+		HomeGoals *int               `json:"home_goals"`
+		AwayGoals *int               `json:"away_goals"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	if !req.Status.Valid() {
+		http.Error(w, "Unknown status", 400)
+		return
+	}
+	hasScore := req.HomeGoals != nil && req.AwayGoals != nil
+	if hasScore && (*req.HomeGoals < 0 || *req.AwayGoals < 0) {
+		http.Error(w, "Goals cannot be negative", 400)
+		return
+	}
+	if req.Status.CountsInTable() && !hasScore {
+		http.Error(w, "A "+string(req.Status)+" match needs home_goals and away_goals", 400)
+		return
+	}
+	league, i, ok := openMatchLeague(w, match)
+	if !ok {
+		return
+	}
+	m := &league.Matches[i]
+	switch {
+	case req.Status == models.StatusCompleted:
+		m.SetResult(*req.HomeGoals, *req.AwayGoals)
+	case req.Status == models.StatusAwarded:
+		m.Award(*req.HomeGoals, *req.AwayGoals)
+	default:
+		m.SetStatus(req.Status)
+		if req.Status == models.StatusAbandoned && hasScore {
+			m.SetLiveScore(*req.HomeGoals, *req.AwayGoals)
+		}
+	}
+	if err := rateLeague(&league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if !saveLeague(w, league) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"match":     matchToJSON(*m),
+		"standings": buildStandings(league.CalculateTable()),
+	})
+}
+
+// replayMatch simulates a postponed or abandoned match
+func replayMatch(w http.ResponseWriter, match models.Match) {
+	if match.Status != models.StatusPostponed && match.Status != models.StatusAbandoned {
+		http.Error(w, "Only postponed or abandoned matches can be replayed", http.StatusConflict)
+		return
+	}
+	league, i, ok := openMatchLeague(w, match)
+	if !ok {
+		return
+	}
+	avail, err := models.LoadAvailability(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	m := &league.Matches[i]
+	playFixture(league, avail, models.BindSimulator(matchSim, league), m)
+	if err := rateLeague(&league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if !saveLeague(w, league) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"match":     matchToJSON(*m),
+		"standings": buildStandings(league.CalculateTable()),
+	})
+}
+
+// replayPostponed simulates every postponed or abandoned match of the
+// league's current season
+func replayPostponed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	avail, err := models.LoadAvailability(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	teamNames := make(map[int]string)
+	for _, t := range league.Teams {
+		teamNames[t.ID] = t.Name
+	}
+	results := []string{}
+	sim := models.BindSimulator(matchSim, league)
+	for i := range league.Matches {
+		m := &league.Matches[i]
+		if m.Status != models.StatusPostponed && m.Status != models.StatusAbandoned {
+			continue
+		}
+		playFixture(league, avail, sim, m)
+		results = append(results, teamNames[m.HomeTeamID]+" "+strconv.Itoa(int(m.HomeGoals.Int64))+" - "+strconv.Itoa(int(m.AwayGoals.Int64))+" "+teamNames[m.AwayTeamID])
+	}
+	if err := rateLeague(&league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if !saveLeague(w, league) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"standings":     buildStandings(league.CalculateTable()),
+		"match_results": results,
+	})
+}
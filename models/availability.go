@@ -0,0 +1,308 @@
+package models
+
+import (
+	"Case_study/storage"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// AbsenceKind says why a player is unavailable
+type AbsenceKind string
+
+const (
+	AbsenceInjury     AbsenceKind = "injury"
+	AbsenceSuspension AbsenceKind = "suspension"
+)
+
+// Valid reports whether k is a known kind of absence
+func (k AbsenceKind) Valid() bool {
+	return k == AbsenceInjury || k == AbsenceSuspension
+}
+
+// Disciplinary rules: a red card bans a player for RedCardBan matches and
+// every YellowCardLimit-th yellow card of a season for AccumulationBan
+// matches
+const (
+	RedCardBan      = 1
+	YellowCardLimit = 5
+	AccumulationBan = 1
+)
+
+// Absence rules a player out for Weeks weeks starting with FromWeek.
+// Manual absences are stored; the ones that come from matches (injuries,
+// red cards and yellow card accumulation) are derived from the events of
+// completed matches and carry the match's ID. A ban from a match is for
+// Weeks of the team's fixtures rather than calendar weeks and is served in
+// the weeks listed in ServedWeeks.
+type Absence struct {
+	ID       int
+	PlayerID int
+	TeamID   int
+	SeasonID int
+	Kind     AbsenceKind
+	FromWeek int
+	Weeks    int
+	Reason   string
+	// MatchID is the match the absence comes from, zero for manual ones
+	MatchID int
+	// ServedWeeks are the weeks of the fixtures a ban from a match covers
+	ServedWeeks []int
+}
+
+// Covers reports whether the absence rules the player out in week
+func (a Absence) Covers(week int) bool {
+	if a.Kind == AbsenceSuspension && a.MatchID != 0 {
+		for _, w := range a.ServedWeeks {
+			if w == week {
+				return true
+			}
+		}
+		return false
+	}
+	return week >= a.FromWeek && week < a.FromWeek+a.Weeks
+}
+
+// Validate rejects absences of an unknown kind or without a duration
+func (a Absence) Validate() error {
+	if !a.Kind.Valid() {
+		return fmt.Errorf("kind must be injury or suspension")
+	}
+	if a.FromWeek < 1 {
+		return fmt.Errorf("from_week must be at least 1")
+	}
+	if a.Weeks < 1 {
+		return fmt.Errorf("weeks must be at least 1")
+	}
+	return nil
+}
+
+// SeasonAbsences lists every absence of a season: the manual ones plus
+// those that follow from the completed matches. A match's events are taken
+// from Match.Events when set, so results simulated but not yet stored
+// count, and from stored otherwise. Injuries start the week after the
+// match. Bans are served in the team's next fixtures after it, so a bye
+// does not count, and neither does a fixture that is postponed or
+// abandoned and waiting to be replayed.
+func SeasonAbsences(matches []Match, stored []MatchEvent, manual []Absence) []Absence {
+	byMatch := make(map[int][]MatchEvent)
+	for _, e := range stored {
+		byMatch[e.MatchID] = append(byMatch[e.MatchID], e)
+	}
+	played := []Match{}
+	for _, m := range matches {
+		if m.Status == StatusCompleted {
+			played = append(played, m)
+		}
+	}
+	// Yellow cards accumulate in the order the matches were played
+	sort.SliceStable(played, func(i, j int) bool { return played[i].Week < played[j].Week })
+	absences := append([]Absence(nil), manual...)
+	yellows := make(map[int]int)
+	for _, m := range played {
+		events := m.Events
+		if events == nil {
+			events = byMatch[m.ID]
+		}
+		for _, e := range events {
+			if e.PlayerID == 0 {
+				continue
+			}
+			a := Absence{PlayerID: e.PlayerID, TeamID: e.TeamID, SeasonID: m.SeasonID, FromWeek: m.Week + 1, MatchID: m.ID}
+			switch e.Type {
+			case EventInjury:
+				if e.Weeks == 0 {
+					continue
+				}
+				a.Kind, a.Weeks, a.Reason = AbsenceInjury, e.Weeks, fmt.Sprintf("injured in week %d", m.Week)
+			case EventRedCard:
+				a.Kind, a.Weeks, a.Reason = AbsenceSuspension, RedCardBan, fmt.Sprintf("sent off in week %d", m.Week)
+			case EventYellowCard:
+				yellows[e.PlayerID]++
+				if yellows[e.PlayerID]%YellowCardLimit != 0 {
+					continue
+				}
+				a.Kind, a.Weeks, a.Reason = AbsenceSuspension, AccumulationBan, fmt.Sprintf("%d yellow cards", yellows[e.PlayerID])
+			default:
+				continue
+			}
+			if a.Kind == AbsenceSuspension {
+				a.ServedWeeks = nextFixtureWeeks(matches, m, e.TeamID, a.Weeks)
+				if len(a.ServedWeeks) > 0 {
+					a.FromWeek = a.ServedWeeks[0]
+				}
+			}
+			absences = append(absences, a)
+		}
+	}
+	return absences
+}
+
+// nextFixtureWeeks returns the weeks of the team's next n fixtures after
+// the match, skipping ones postponed or abandoned. The list is shorter when
+// the season ends first.
+func nextFixtureWeeks(matches []Match, after Match, teamID int, n int) []int {
+	fixtures := []Match{}
+	for _, m := range matches {
+		if m.Week <= after.Week || (m.HomeTeamID != teamID && m.AwayTeamID != teamID) {
+			continue
+		}
+		if m.Status == StatusPostponed || m.Status == StatusAbandoned {
+			continue
+		}
+		fixtures = append(fixtures, m)
+	}
+	sort.SliceStable(fixtures, func(i, j int) bool {
+		if fixtures[i].Week != fixtures[j].Week {
+			return fixtures[i].Week < fixtures[j].Week
+		}
+		return fixtures[i].ID < fixtures[j].ID
+	})
+	weeks := []int{}
+	for _, m := range fixtures {
+		if len(weeks) == n {
+			break
+		}
+		weeks = append(weeks, m.Week)
+	}
+	return weeks
+}
+
+// AvailableTeams returns copies of the teams without the players ruled out
+// in week. A chosen lineup that loses a player falls back to the best
+// available eleven, and the team's AvailabilityFactor drops by the share of
+// its starting eleven's ratings that eleven loses, so simulators that only
+// look at strength are weakened too.
+func AvailableTeams(teams []Team, absences []Absence, week int) []Team {
+	out := make(map[int]bool)
+	for _, a := range absences {
+		if a.Covers(week) {
+			out[a.PlayerID] = true
+		}
+	}
+	result := make([]Team, len(teams))
+	for i, t := range teams {
+		result[i] = t
+		if len(out) == 0 {
+			continue
+		}
+		result[i].Players = nil
+		for _, p := range t.Players {
+			if !out[p.ID] {
+				result[i].Players = append(result[i].Players, p)
+			}
+		}
+		if len(result[i].Players) < len(t.Players) {
+			result[i].AvailabilityFactor = availabilityFactor(t.Players, result[i].Players)
+		}
+	}
+	return result
+}
+
+// availabilityFactor returns the ratings of the eleven the available
+// players field over those of the full squad's eleven, at most one
+func availabilityFactor(squad []Player, available []Player) float64 {
+	full := elevenRating(StartingEleven(squad))
+	if full == 0 {
+		return 1
+	}
+	return math.Min(elevenRating(StartingEleven(available))/full, 1)
+}
+
+// elevenRating adds up the players' ratings in their positions
+func elevenRating(eleven []Player) float64 {
+	total := 0
+	for _, p := range eleven {
+		total += p.overall()
+	}
+	return float64(total)
+}
+
+// Availability holds what is needed to work out a season's absences as
+// more matches are played
+type Availability struct {
+	Stored []MatchEvent
+	Manual []Absence
+}
+
+// LoadAvailability reads a season's stored events and manual absences
+func LoadAvailability(seasonID int) (Availability, error) {
+	var a Availability
+	var err error
+	if a.Stored, err = (SQLiteEventRepository{}).GetEventsBySeason(seasonID); err != nil {
+		return a, err
+	}
+	a.Manual, err = SQLiteAbsenceRepository{}.GetAbsencesBySeason(seasonID)
+	return a, err
+}
+
+// Absences returns the season's absences given its matches
+func (a Availability) Absences(matches []Match) []Absence {
+	return SeasonAbsences(matches, a.Stored, a.Manual)
+}
+
+// Teams returns the league's teams without the players ruled out in week
+func (a Availability) Teams(league League, week int) []Team {
+	return AvailableTeams(league.Teams, a.Absences(league.Matches), week)
+}
+
+// SQLiteAbsenceRepository stores manually entered absences
+type SQLiteAbsenceRepository struct{}
+
+const absenceColumns = "a.id, a.player_id, p.team_id, a.season_id, a.kind, a.from_week, a.weeks, a.reason"
+
+// GetAbsencesBySeason returns the manual absences of a season
+func (r SQLiteAbsenceRepository) GetAbsencesBySeason(seasonID int) ([]Absence, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT "+absenceColumns+` FROM player_absences a JOIN players p ON p.id = a.player_id
+		WHERE a.season_id = ? ORDER BY a.from_week, a.id`, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	absences := []Absence{}
+	for rows.Next() {
+		var a Absence
+		if err := rows.Scan(&a.ID, &a.PlayerID, &a.TeamID, &a.SeasonID, &a.Kind, &a.FromWeek, &a.Weeks, &a.Reason); err != nil {
+			return nil, err
+		}
+		absences = append(absences, a)
+	}
+	return absences, rows.Err()
+}
+
+// CreateAbsence stores a manual absence and returns its ID
+func (r SQLiteAbsenceRepository) CreateAbsence(a Absence) (int, error) {
+	db := storage.GetDB()
+	res, err := db.Exec("INSERT INTO player_absences (player_id, season_id, kind, from_week, weeks, reason) VALUES (?, ?, ?, ?, ?, ?)",
+		a.PlayerID, a.SeasonID, a.Kind, a.FromWeek, a.Weeks, a.Reason)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// DeleteAbsence removes one of a player's manual absences and returns
+// ErrNotFound if the player has no such absence
+func (r SQLiteAbsenceRepository) DeleteAbsence(playerID int, id int) error {
+	db := storage.GetDB()
+	res, err := db.Exec("DELETE FROM player_absences WHERE id = ? AND player_id = ?", id, playerID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// deletePlayerAbsences removes a player's manual absences
+func deletePlayerAbsences(tx storage.DBTX, playerID int) error {
+	_, err := tx.Exec("DELETE FROM player_absences WHERE player_id = ?", playerID)
+	return err
+}
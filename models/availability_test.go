@@ -0,0 +1,68 @@
+package models
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSeasonAbsencesBanFollowsFixtures(t *testing.T) {
+	sentOff := Match{ID: 1, Week: 1, HomeTeamID: 1, AwayTeamID: 2}
+	sentOff.SetResult(0, 1)
+	sentOff.Events = []MatchEvent{{MatchID: 1, Type: EventRedCard, TeamID: 1, PlayerID: 7}}
+	matches := []Match{
+		sentOff,
+		// Team 1 has a bye in week 2 and its week 3 fixture is postponed
+		{ID: 2, Week: 2, HomeTeamID: 2, AwayTeamID: 3},
+		{ID: 3, Week: 3, HomeTeamID: 1, AwayTeamID: 3, Status: StatusPostponed},
+		{ID: 4, Week: 4, HomeTeamID: 3, AwayTeamID: 1},
+		{ID: 5, Week: 5, HomeTeamID: 1, AwayTeamID: 2},
+	}
+	absences := SeasonAbsences(matches, nil, nil)
+	if len(absences) != 1 {
+		t.Fatalf("%d absences, want 1", len(absences))
+	}
+	ban := absences[0]
+	if !reflect.DeepEqual(ban.ServedWeeks, []int{4}) || ban.FromWeek != 4 {
+		t.Errorf("ban served in weeks %v from week %d, want [4] from 4", ban.ServedWeeks, ban.FromWeek)
+	}
+	for week, want := range map[int]bool{2: false, 3: false, 4: true, 5: false} {
+		if got := ban.Covers(week); got != want {
+			t.Errorf("ban covers week %d: %v, want %v", week, got, want)
+		}
+	}
+}
+
+func TestAvailableTeamsWeakenDepletedSide(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	home := Team{ID: 1, Strength: 75}
+	// Just eleven players, so nobody on the bench can step in
+	for i, p := range StartingEleven(GenerateSquad(home, rng)) {
+		p.ID, p.TeamID = i+1, home.ID
+		home.Players = append(home.Players, p)
+	}
+	away := Team{ID: 2, Strength: 75}
+	// Rule out three of the home side's starters
+	absences := []Absence{}
+	for _, p := range home.Players[1:4] {
+		absences = append(absences, Absence{PlayerID: p.ID, TeamID: home.ID, Kind: AbsenceInjury, FromWeek: 1, Weeks: 1})
+	}
+	depleted := AvailableTeams([]Team{home, away}, absences, 1)[0]
+	if depleted.AvailabilityFactor <= 0 || depleted.AvailabilityFactor >= 1 {
+		t.Fatalf("availability factor %.3f, want between 0 and 1", depleted.AvailabilityFactor)
+	}
+	sims := map[string]MatchSimulator{
+		"basic":   BasicMatchSimulator{},
+		"poisson": NewPoissonMatchSimulator(),
+	}
+	for name, sim := range sims {
+		full := PredictMatch(sim, home, away, 20000, rand.New(rand.NewSource(2)))
+		weak := PredictMatch(sim, depleted, away, 20000, rand.New(rand.NewSource(2)))
+		if weak.HomeXG >= full.HomeXG {
+			t.Errorf("%s: depleted side's xG %.3f, full side's %.3f", name, weak.HomeXG, full.HomeXG)
+		}
+	}
+	if AvailableTeams([]Team{home, away}, absences, 2)[0].AvailabilityFactor != 0 {
+		t.Error("team weakened in a week nobody is missing")
+	}
+}
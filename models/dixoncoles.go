@@ -0,0 +1,537 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// DixonColesParams are the fitted parameters of the Dixon-Coles model. A
+// home side's goals are Poisson with mean
+// exp(Intercept + HomeAdvantage + Attack[home] - Defence[away]) and the away
+// side's with mean exp(Intercept + Attack[away] - Defence[home]); Rho
+// corrects the odds of the 0-0, 1-0, 0-1 and 1-1 scores. Attack and defence
+// are on a log scale and sum to zero, so zero is an average team and a
+// higher defence concedes fewer.
+type DixonColesParams struct {
+	Attack        map[int]float64
+	Defence       map[int]float64
+	Intercept     float64
+	HomeAdvantage float64
+	Rho           float64
+	// Matches is the number of results fitted and Weight their total
+	// time-decay weight
+	Matches int
+	Weight  float64
+	// LogLikelihood is the weighted log-likelihood of the results
+	LogLikelihood float64
+	Iterations    int
+	Converged     bool
+}
+
+// ExpectedGoals returns the Poisson means of a match between two teams.
+// Teams the fit has not seen are average.
+func (p *DixonColesParams) ExpectedGoals(homeID int, awayID int) (float64, float64) {
+	homeXG := math.Exp(p.Intercept + p.HomeAdvantage + p.Attack[homeID] - p.Defence[awayID])
+	awayXG := math.Exp(p.Intercept + p.Attack[awayID] - p.Defence[homeID])
+	return homeXG, awayXG
+}
+
+// dixonColesTau is the low-score correction of a scoreline
+func dixonColesTau(x int, y int, homeXG float64, awayXG float64, rho float64) float64 {
+	switch {
+	case x == 0 && y == 0:
+		return 1 - homeXG*awayXG*rho
+	case x == 0 && y == 1:
+		return 1 + homeXG*rho
+	case x == 1 && y == 0:
+		return 1 + awayXG*rho
+	case x == 1 && y == 1:
+		return 1 - rho
+	}
+	return 1
+}
+
+// ScoreMatrix returns the probability of every scoreline up to maxGoals
+// goals a side, indexed [home][away], for Poisson means with the
+// Dixon-Coles correction rho (zero for independent Poisson). The
+// probabilities are normalised so they sum to one.
+func ScoreMatrix(homeXG float64, awayXG float64, rho float64, maxGoals int) [][]float64 {
+	homeP, awayP := poissonPMF(homeXG, maxGoals), poissonPMF(awayXG, maxGoals)
+	matrix := make([][]float64, maxGoals+1)
+	total := 0.0
+	for x := range matrix {
+		matrix[x] = make([]float64, maxGoals+1)
+		for y := range matrix[x] {
+			p := homeP[x] * awayP[y] * dixonColesTau(x, y, homeXG, awayXG, rho)
+			if p < 0 {
+				p = 0
+			}
+			matrix[x][y] = p
+			total += p
+		}
+	}
+	if total > 0 {
+		for x := range matrix {
+			for y := range matrix[x] {
+				matrix[x][y] /= total
+			}
+		}
+	}
+	return matrix
+}
+
+// poissonPMF returns P(k) for k = 0..max
+func poissonPMF(lambda float64, max int) []float64 {
+	pmf := make([]float64, max+1)
+	pmf[0] = math.Exp(-lambda)
+	for k := 1; k <= max; k++ {
+		pmf[k] = pmf[k-1] * lambda / float64(k)
+	}
+	return pmf
+}
+
+// DixonColesOptions tune the fit
+type DixonColesOptions struct {
+	// HalfLife is the number of weeks after which a result counts half as
+	// much; zero weights every result the same
+	HalfLife float64
+	// Shrinkage pulls attack and defence towards the teams' relative
+	// strength, home advantage towards HomeAdvantage and rho towards zero,
+	// which keeps the fit finite for a team that has never conceded and
+	// sane after a handful of results. The larger it is, the more results it takes to move a
+	// parameter away from its prior.
+	Shrinkage float64
+	// AverageGoals is the scoring rate per side the intercept is shrunk
+	// towards, so a run of goalless draws cannot stop a season's scoring;
+	// zero leaves the intercept to the results alone
+	AverageGoals float64
+	// HomeAdvantage is the multiplier on the home side's goals that the
+	// home advantage is shrunk towards; zero shrinks it towards none
+	HomeAdvantage float64
+}
+
+// Dixon-Coles defaults
+const (
+	DefaultDixonColesHalfLife  = 15
+	DefaultDixonColesShrinkage = 3
+	dixonColesMaxIterations    = 5000
+	dixonColesTolerance        = 1e-10
+	// dixonColesMaxGoals bounds the scorelines the simulator samples from
+	dixonColesMaxGoals = 10
+)
+
+// dixonColesResult is a completed match with its time-decay weight
+type dixonColesResult struct {
+	home, away int
+	x, y       int
+	weight     float64
+}
+
+// matchTimes places every match on a single timeline of weeks, with the
+// seasons in the order they were created, one after the other
+func matchTimes(matches []Match) map[int]float64 {
+	span := make(map[int]int)
+	for _, m := range matches {
+		if m.Week > span[m.SeasonID] {
+			span[m.SeasonID] = m.Week
+		}
+	}
+	seasons := make([]int, 0, len(span))
+	for id := range span {
+		seasons = append(seasons, id)
+	}
+	sort.Ints(seasons)
+	offset := make(map[int]int)
+	total := 0
+	for _, id := range seasons {
+		offset[id] = total
+		total += span[id]
+	}
+	times := make(map[int]float64)
+	for _, m := range matches {
+		times[m.ID] = float64(offset[m.SeasonID] + m.Week)
+	}
+	return times
+}
+
+// FitDixonColes fits the model by maximum likelihood to the completed
+// matches, weighting each by how many weeks before the latest one it was
+// played. Attack and defence are shrunk towards the teams' relative
+// Strength, log(strength / geometric mean), which is what the Poisson model
+// plays them at, so a few lucky results cannot turn the table upside
+// down. It returns nil when there are no completed matches. Matches of
+// several seasons can be passed together; later seasons count as later.
+func FitDixonColes(matches []Match, teams []Team, opts DixonColesOptions) *DixonColesParams {
+	times := matchTimes(matches)
+	latest := math.Inf(-1)
+	for _, m := range matches {
+		if m.Status == StatusCompleted && times[m.ID] > latest {
+			latest = times[m.ID]
+		}
+	}
+	var results []dixonColesResult
+	index := make(map[int]int)
+	var ids []int
+	teamIndex := func(id int) int {
+		i, ok := index[id]
+		if !ok {
+			i = len(ids)
+			index[id] = i
+			ids = append(ids, id)
+		}
+		return i
+	}
+	for _, t := range teams {
+		teamIndex(t.ID)
+	}
+	totalWeight, totalGoals := 0.0, 0.0
+	for _, m := range matches {
+		if m.Status != StatusCompleted {
+			continue
+		}
+		w := 1.0
+		if opts.HalfLife > 0 {
+			w = math.Pow(0.5, (latest-times[m.ID])/opts.HalfLife)
+		}
+		r := dixonColesResult{home: teamIndex(m.HomeTeamID), away: teamIndex(m.AwayTeamID), x: int(m.HomeGoals.Int64), y: int(m.AwayGoals.Int64), weight: w}
+		results = append(results, r)
+		totalWeight += w
+		totalGoals += w * float64(r.x+r.y)
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	f := dixonColesFit{results: results, teams: len(ids), shrinkage: opts.Shrinkage, averageGoals: opts.AverageGoals, homeAdvantage: opts.HomeAdvantage, totalWeight: totalWeight}
+	f.strengthPrior = strengthPrior(ids, teams)
+	x := make([]float64, 2*f.teams+3)
+	// Start from the strength prior scoring the observed average
+	for i, p := range f.strengthPrior {
+		x[f.attack(i)], x[f.defence(i)] = p, p
+	}
+	x[f.intercept()] = math.Log(math.Max(totalGoals/totalWeight/2, 0.1))
+	if f.homeAdvantage > 0 {
+		x[f.home()] = math.Log(f.homeAdvantage)
+	}
+	value := f.objective(x)
+	step := 1.0
+	params := &DixonColesParams{Matches: len(results), Weight: totalWeight}
+	for params.Iterations < dixonColesMaxIterations {
+		params.Iterations++
+		grad := f.gradient(x)
+		improved := false
+		for step > 1e-12 {
+			next := make([]float64, len(x))
+			for i := range x {
+				next[i] = x[i] + step*grad[i]
+			}
+			f.center(next)
+			if v := f.objective(next); v > value {
+				gain := v - value
+				x, value, improved = next, v, true
+				step *= 1.5
+				if gain < dixonColesTolerance {
+					params.Converged = true
+				}
+				break
+			}
+			step /= 2
+		}
+		if !improved {
+			// No step improves the fit any more, so it is at the optimum
+			params.Converged = true
+		}
+		if params.Converged {
+			break
+		}
+	}
+	params.Attack = make(map[int]float64)
+	params.Defence = make(map[int]float64)
+	for i, id := range ids {
+		params.Attack[id] = x[f.attack(i)]
+		params.Defence[id] = x[f.defence(i)]
+	}
+	params.Intercept = x[f.intercept()]
+	params.HomeAdvantage = x[f.home()]
+	params.Rho = x[f.rho()]
+	params.LogLikelihood = f.logLikelihood(x)
+	return params
+}
+
+// dixonColesFit holds the data of a fit. The parameter vector holds every
+// team's attack, then every team's defence, then the intercept, home
+// advantage and rho.
+type dixonColesFit struct {
+	results []dixonColesResult
+	teams   int
+	// strengthPrior is each team's log strength relative to the others,
+	// which attack and defence are shrunk towards
+	strengthPrior []float64
+	shrinkage     float64
+	averageGoals  float64
+	homeAdvantage float64
+	totalWeight   float64
+}
+
+func (f dixonColesFit) attack(i int) int  { return i }
+func (f dixonColesFit) defence(i int) int { return f.teams + i }
+func (f dixonColesFit) intercept() int    { return 2 * f.teams }
+func (f dixonColesFit) home() int         { return 2*f.teams + 1 }
+func (f dixonColesFit) rho() int          { return 2*f.teams + 2 }
+
+func (f dixonColesFit) means(x []float64, r dixonColesResult) (float64, float64) {
+	homeXG := math.Exp(x[f.intercept()] + x[f.home()] + x[f.attack(r.home)] - x[f.defence(r.away)])
+	awayXG := math.Exp(x[f.intercept()] + x[f.attack(r.away)] - x[f.defence(r.home)])
+	return homeXG, awayXG
+}
+
+// logLikelihood is the weighted log-likelihood, or -Inf where rho is out of
+// range. The correction only keeps the scoreline probabilities summing to
+// one while none of the low scores is made impossible, in any match, so
+// rho is bounded by every match and not just by the scores seen.
+func (f dixonColesFit) logLikelihood(x []float64) float64 {
+	total := 0.0
+	rho := x[f.rho()]
+	for _, r := range f.results {
+		homeXG, awayXG := f.means(x, r)
+		if 1-homeXG*awayXG*rho <= 0 || 1+homeXG*rho <= 0 || 1+awayXG*rho <= 0 || 1-rho <= 0 {
+			return math.Inf(-1)
+		}
+		tau := dixonColesTau(r.x, r.y, homeXG, awayXG, rho)
+		homeFact, _ := math.Lgamma(float64(r.x + 1))
+		awayFact, _ := math.Lgamma(float64(r.y + 1))
+		ll := math.Log(tau) + float64(r.x)*math.Log(homeXG) - homeXG - homeFact + float64(r.y)*math.Log(awayXG) - awayXG - awayFact
+		total += r.weight * ll
+	}
+	return total
+}
+
+// prior returns how far a parameter is from the value it is shrunk towards
+func (f dixonColesFit) prior(x []float64, i int) float64 {
+	if i < 2*f.teams {
+		return x[i] - f.strengthPrior[i%f.teams]
+	}
+	if i == f.home() && f.homeAdvantage > 0 {
+		return x[i] - math.Log(f.homeAdvantage)
+	}
+	if i != f.intercept() {
+		return x[i]
+	}
+	if f.averageGoals <= 0 {
+		return 0
+	}
+	return x[i] - math.Log(f.averageGoals)
+}
+
+// objective is the penalised log-likelihood per unit of weight that the fit
+// maximises
+func (f dixonColesFit) objective(x []float64) float64 {
+	penalty := 0.0
+	for i := range x {
+		d := f.prior(x, i)
+		penalty += d * d
+	}
+	return (f.logLikelihood(x) - 0.5*f.shrinkage*penalty) / f.totalWeight
+}
+
+func (f dixonColesFit) gradient(x []float64) []float64 {
+	grad := make([]float64, len(x))
+	rho := x[f.rho()]
+	for _, r := range f.results {
+		homeXG, awayXG := f.means(x, r)
+		tau := dixonColesTau(r.x, r.y, homeXG, awayXG, rho)
+		// Derivatives of log(tau) with respect to the log means and rho
+		var dHome, dAway, dRho float64
+		switch {
+		case r.x == 0 && r.y == 0:
+			dHome, dAway, dRho = -homeXG*awayXG*rho/tau, -homeXG*awayXG*rho/tau, -homeXG*awayXG/tau
+		case r.x == 0 && r.y == 1:
+			dHome, dRho = homeXG*rho/tau, homeXG/tau
+		case r.x == 1 && r.y == 0:
+			dAway, dRho = awayXG*rho/tau, awayXG/tau
+		case r.x == 1 && r.y == 1:
+			dRho = -1 / tau
+		}
+		gHome := r.weight * (float64(r.x) - homeXG + dHome)
+		gAway := r.weight * (float64(r.y) - awayXG + dAway)
+		grad[f.intercept()] += gHome + gAway
+		grad[f.home()] += gHome
+		grad[f.attack(r.home)] += gHome
+		grad[f.defence(r.away)] -= gHome
+		grad[f.attack(r.away)] += gAway
+		grad[f.defence(r.home)] -= gAway
+		grad[f.rho()] += r.weight * dRho
+	}
+	for i := range grad {
+		grad[i] -= f.shrinkage * f.prior(x, i)
+		grad[i] /= f.totalWeight
+	}
+	return grad
+}
+
+// center makes attack and defence sum to zero, moving the difference into
+// the intercept so no expected goals change
+func (f dixonColesFit) center(x []float64) {
+	attack, defence := 0.0, 0.0
+	for i := 0; i < f.teams; i++ {
+		attack += x[f.attack(i)]
+		defence += x[f.defence(i)]
+	}
+	attack /= float64(f.teams)
+	defence /= float64(f.teams)
+	for i := 0; i < f.teams; i++ {
+		x[f.attack(i)] -= attack
+		x[f.defence(i)] -= defence
+	}
+	x[f.intercept()] += attack - defence
+}
+
+// strengthPrior returns log(strength / geometric mean) for the teams in
+// ids, centred to sum to zero like the fitted ratings. Teams without a
+// known strength, such as ones only met in earlier seasons, are average.
+func strengthPrior(ids []int, teams []Team) []float64 {
+	logStrength := make(map[int]float64)
+	for _, t := range teams {
+		if t.Strength > 0 {
+			logStrength[t.ID] = math.Log(float64(t.Strength))
+		}
+	}
+	mean := 0.0
+	for _, v := range logStrength {
+		mean += v
+	}
+	if len(logStrength) > 0 {
+		mean /= float64(len(logStrength))
+	}
+	prior := make([]float64, len(ids))
+	centre := 0.0
+	for i, id := range ids {
+		if v, ok := logStrength[id]; ok {
+			prior[i] = v - mean
+		}
+		centre += prior[i]
+	}
+	centre /= float64(len(ids))
+	for i := range prior {
+		prior[i] -= centre
+	}
+	return prior
+}
+
+// LeagueHistory returns the matches of a league's seasons before its
+// current one, oldest first
+func LeagueHistory(league League) ([]Match, error) {
+	seasons, err := SQLiteSeasonRepository{}.GetSeasonsByLeague(league.ID)
+	if err != nil {
+		return nil, err
+	}
+	history := []Match{}
+	for _, s := range seasons {
+		if s.ID >= league.SeasonID {
+			continue
+		}
+		matches, err := SQLiteMatchRepository{}.GetMatchesBySeason(s.ID)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, matches...)
+	}
+	return history, nil
+}
+
+// FitLeague fits the model to the league's current season and the seasons
+// before it
+func FitLeague(league League, opts DixonColesOptions) (*DixonColesParams, error) {
+	history, err := LeagueHistory(league)
+	if err != nil {
+		return nil, err
+	}
+	return FitDixonColes(append(history, league.Matches...), league.Teams, opts), nil
+}
+
+// DixonColesMatchSimulator plays matches with a Dixon-Coles model fitted to
+// the league's results instead of the teams' strength. It is fitted when
+// bound to a league with ForLeague and keeps its fit when bound again, so
+// a Monte Carlo run does not refit it on simulated results. Before any
+// match has been played, or unbound, it plays like the Poisson model. Each
+// side's expected goals are scaled by its StrengthFactor over the
+// opponent's.
+type DixonColesMatchSimulator struct {
+	PoissonMatchSimulator
+	Options DixonColesOptions
+	Params  *DixonColesParams
+}
+
+// NewDixonColesMatchSimulator returns an unfitted simulator with the
+// default options
+func NewDixonColesMatchSimulator() DixonColesMatchSimulator {
+	poisson := NewPoissonMatchSimulator()
+	return DixonColesMatchSimulator{
+		PoissonMatchSimulator: poisson,
+		Options:               DixonColesOptions{HalfLife: DefaultDixonColesHalfLife, Shrinkage: DefaultDixonColesShrinkage, AverageGoals: poisson.AverageGoals, HomeAdvantage: poisson.HomeAdvantage},
+	}
+}
+
+// ForLeague fits the model to the league unless it has been fitted already.
+// If earlier seasons cannot be read the current one is fitted on its own.
+func (d DixonColesMatchSimulator) ForLeague(league League) MatchSimulator {
+	if d.Params != nil {
+		return d
+	}
+	params, err := FitLeague(league, d.Options)
+	if err != nil {
+		params = FitDixonColes(league.Matches, league.Teams, d.Options)
+	}
+	d.Params = params
+	return d
+}
+
+// ExpectedGoals returns the Poisson means for the home and away side,
+// capped at MaxExpectedGoals
+func (d DixonColesMatchSimulator) ExpectedGoals(home Team, away Team) (float64, float64) {
+	if d.Params == nil {
+		return d.PoissonMatchSimulator.ExpectedGoals(home, away)
+	}
+	homeXG, awayXG := d.Params.ExpectedGoals(home.ID, away.ID)
+	return capExpectedGoals(homeXG * home.factor() / away.factor()), capExpectedGoals(awayXG * away.factor() / home.factor())
+}
+
+// Rho returns the low-score correction, zero until the model is fitted
+func (d DixonColesMatchSimulator) Rho() float64 {
+	if d.Params == nil {
+		return 0
+	}
+	return d.Params.Rho
+}
+
+// SimulateMatch returns simulated goals for home and away teams
+func (d DixonColesMatchSimulator) SimulateMatch(home Team, away Team) (int, int) {
+	return d.SimulateMatchWithRand(home, away, newRand())
+}
+
+// SimulateMatchWithRand is SimulateMatch drawing from the given generator.
+// A fitted model draws the scoreline from the corrected joint distribution.
+func (d DixonColesMatchSimulator) SimulateMatchWithRand(home Team, away Team, rng *rand.Rand) (int, int) {
+	homeXG, awayXG := d.ExpectedGoals(home, away)
+	if d.Params == nil {
+		return samplePoisson(homeXG, rng), samplePoisson(awayXG, rng)
+	}
+	matrix := ScoreMatrix(homeXG, awayXG, d.Params.Rho, dixonColesMaxGoals)
+	u := rng.Float64()
+	// Rounding can leave u just above the total, so fall back to the last
+	// possible scoreline
+	lastX, lastY := 0, 0
+	for x := range matrix {
+		for y, p := range matrix[x] {
+			if p <= 0 {
+				continue
+			}
+			if u < p {
+				return x, y
+			}
+			u -= p
+			lastX, lastY = x, y
+		}
+	}
+	return lastX, lastY
+}
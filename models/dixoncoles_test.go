@@ -0,0 +1,56 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// A season played through with the model refitted to its own simulated
+// results before every match should still leave the strong sides above the weak ones
+func TestDixonColesSeasonFollowsStrength(t *testing.T) {
+	teams := []Team{}
+	for i := 0; i < 20; i++ {
+		teams = append(teams, Team{ID: i + 1, Strength: 50 + 2*i})
+	}
+	league := League{Teams: teams, Matches: GenerateDoubleRoundRobin(teams)}
+	rng := rand.New(rand.NewSource(1))
+	for i := range league.Matches {
+		m := &league.Matches[i]
+		sim := NewDixonColesMatchSimulator()
+		sim.Params = FitDixonColes(league.Matches, teams, sim.Options)
+		home, away := sim.SimulateMatchWithRand(teams[m.HomeTeamID-1], teams[m.AwayTeamID-1], rng)
+		m.SetResult(home, away)
+	}
+	points := make(map[int]int)
+	for _, e := range league.CalculateTable() {
+		points[e.TeamID] = e.Points
+	}
+	weak, strong := 0, 0
+	for i := 1; i <= 5; i++ {
+		weak += points[i]
+		strong += points[21-i]
+	}
+	if 4*strong <= 5*weak {
+		t.Errorf("five strongest teams took %d points, five weakest %d", strong, weak)
+	}
+}
+
+// With a single goalless draw to go on the ratings should stay close to
+// the strength ratio, log(90/60) apart
+func TestDixonColesPriorFollowsStrength(t *testing.T) {
+	teams := []Team{{ID: 1, Strength: 90}, {ID: 2, Strength: 60}}
+	matches := []Match{{ID: 1, Week: 1, HomeTeamID: 1, AwayTeamID: 2}}
+	matches[0].SetResult(0, 0)
+	params := FitDixonColes(matches, teams, NewDixonColesMatchSimulator().Options)
+	if params == nil {
+		t.Fatal("no fit")
+	}
+	gap := math.Log(90.0 / 60.0)
+	if d := params.Attack[1] - params.Attack[2]; d < gap/2 || d > gap {
+		t.Errorf("attack gap %.3f, want between %.3f and %.3f", d, gap/2, gap)
+	}
+	if d := params.Defence[1] - params.Defence[2]; d < gap/2 || d > gap {
+		t.Errorf("defence gap %.3f, want between %.3f and %.3f", d, gap/2, gap)
+	}
+}
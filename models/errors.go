@@ -0,0 +1,22 @@
+package models
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when a requested record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrNoActiveSeason is returned when every season has been closed
+	ErrNoActiveSeason = errors.New("no active season")
+	// ErrTeamHasPlayed is returned when deleting a team with a match that
+	// has started, whose result or events involve another team too
+	ErrTeamHasPlayed = errors.New("team has started matches")
+	// ErrInvalidTransition is returned when a match cannot move to the
+	// requested status
+	ErrInvalidTransition = errors.New("invalid match status transition")
+)
+
+// Strength bounds accepted for a team
+const (
+	MinStrength = 1
+	MaxStrength = 100
+)
@@ -0,0 +1,317 @@
+package models
+
+import (
+	"Case_study/storage"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// MatchEvent is something that happened in a stored match
+type MatchEvent struct {
+	ID      int
+	MatchID int
+	Minute  int
+	Type    EventType
+	TeamID  int
+	// PlayerID links the event to a squad member; zero when it names
+	// nobody from the squad
+	PlayerID int
+	Player   string
+	// RelatedPlayerID and RelatedPlayer are the goal's assist or the
+	// substitute coming on
+	RelatedPlayerID int
+	RelatedPlayer   string
+	// Weeks is how long an injury rules the player out
+	Weeks int
+	// Extra holds free-form detail such as "penalty" or "own goal"
+	Extra string
+}
+
+// RecordedEventTypes are the event types stored for a match; kick-off and
+// the whistles only exist in the live stream
+var RecordedEventTypes = []EventType{EventLineup, EventGoal, EventYellowCard, EventRedCard, EventSubstitution, EventInjury}
+
+// Recorded reports whether events of this type are stored
+func (t EventType) Recorded() bool {
+	for _, known := range RecordedEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// TimelineEvents turns a live timeline into the events stored for a match
+func TimelineEvents(matchID int, timeline []LiveEvent) []MatchEvent {
+	events := []MatchEvent{}
+	for _, e := range timeline {
+		if e.Type.Recorded() {
+			events = append(events, MatchEvent{
+				MatchID:         matchID,
+				Minute:          e.Minute,
+				Type:            e.Type,
+				TeamID:          e.TeamID,
+				PlayerID:        e.PlayerID,
+				Player:          e.Player,
+				RelatedPlayerID: e.RelatedPlayerID,
+				RelatedPlayer:   e.RelatedPlayer,
+				Weeks:           e.Weeks,
+			})
+		}
+	}
+	return events
+}
+
+// PlayMatch simulates a match with a full timeline and records the result
+// and the events on it
+func PlayMatch(m *Match, home Team, away Team, sim MatchSimulator) {
+	timeline := SimulateLive(home, away, sim, newRand())
+	final := timeline[len(timeline)-1]
+	m.SetResult(final.HomeGoals, final.AwayGoals)
+	m.Events = TimelineEvents(m.ID, timeline)
+}
+
+// ValidateEvents checks manually entered events against a match: every
+// event needs a known type, a minute and one of the two teams, and the
+// goals must add up to the score. Lineup entries are at minute 0.
+func ValidateEvents(m Match, events []MatchEvent) error {
+	homeGoals, awayGoals := 0, 0
+	for _, e := range events {
+		if !e.Type.Recorded() {
+			return fmt.Errorf("unknown event type %q", e.Type)
+		}
+		if e.Type == EventLineup && e.Minute != 0 {
+			return fmt.Errorf("lineup events must be at minute 0")
+		}
+		if e.Type != EventLineup && (e.Minute < 1 || e.Minute > 120) {
+			return fmt.Errorf("event minute must be between 1 and 120")
+		}
+		if e.Weeks < 0 {
+			return fmt.Errorf("injury weeks cannot be negative")
+		}
+		if e.TeamID != m.HomeTeamID && e.TeamID != m.AwayTeamID {
+			return fmt.Errorf("event team %d does not play in match %d", e.TeamID, m.ID)
+		}
+		if e.Type == EventGoal {
+			if e.TeamID == m.HomeTeamID {
+				homeGoals++
+			} else {
+				awayGoals++
+			}
+		}
+	}
+	if int64(homeGoals) != m.HomeGoals.Int64 || int64(awayGoals) != m.AwayGoals.Int64 {
+		return fmt.Errorf("goal events (%d-%d) do not match the score", homeGoals, awayGoals)
+	}
+	return nil
+}
+
+// AttachPlayers checks that every event linked to players names members
+// of that team's squad and fills in their names where they are missing
+func AttachPlayers(events []MatchEvent, home Team, away Team) error {
+	for i, e := range events {
+		squad := home.Players
+		if e.TeamID == away.ID {
+			squad = away.Players
+		}
+		name, err := squadMemberName(squad, e.PlayerID, e.TeamID)
+		if err != nil {
+			return err
+		}
+		if events[i].Player == "" {
+			events[i].Player = name
+		}
+		if name, err = squadMemberName(squad, e.RelatedPlayerID, e.TeamID); err != nil {
+			return err
+		}
+		if events[i].RelatedPlayer == "" {
+			events[i].RelatedPlayer = name
+		}
+	}
+	return nil
+}
+
+// squadMemberName returns the name of player id, or "" for id 0
+func squadMemberName(squad []Player, id int, teamID int) (string, error) {
+	if id == 0 {
+		return "", nil
+	}
+	for _, p := range squad {
+		if p.ID == id {
+			return p.Name, nil
+		}
+	}
+	return "", fmt.Errorf("player %d is not in the squad of team %d", id, teamID)
+}
+
+// SQLiteEventRepository stores match events
+type SQLiteEventRepository struct{}
+
+const eventColumns = "id, match_id, minute, type, team_id, COALESCE(player_id, 0), player, COALESCE(related_player_id, 0), related_player, weeks, extra"
+
+func scanEvents(rows *sql.Rows) ([]MatchEvent, error) {
+	defer rows.Close()
+	events := []MatchEvent{}
+	for rows.Next() {
+		var e MatchEvent
+		if err := rows.Scan(&e.ID, &e.MatchID, &e.Minute, &e.Type, &e.TeamID, &e.PlayerID, &e.Player, &e.RelatedPlayerID, &e.RelatedPlayer, &e.Weeks, &e.Extra); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetEventsByMatch returns a match's timeline in minute order
+func (r SQLiteEventRepository) GetEventsByMatch(matchID int) ([]MatchEvent, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT "+eventColumns+" FROM match_events WHERE match_id = ? ORDER BY minute, id", matchID)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// GetEventsBySeason returns the events of every match in a season
+func (r SQLiteEventRepository) GetEventsBySeason(seasonID int) ([]MatchEvent, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT e."+eventColumns+` FROM match_events e JOIN matches m ON m.id = e.match_id
+		WHERE m.season_id = ? ORDER BY e.match_id, e.minute, e.id`, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// writeMatchEvents replaces a match's stored events
+func writeMatchEvents(tx storage.DBTX, matchID int, events []MatchEvent) error {
+	if _, err := tx.Exec("DELETE FROM match_events WHERE match_id = ?", matchID); err != nil {
+		return err
+	}
+	for _, e := range events {
+		_, err := tx.Exec(`INSERT INTO match_events (match_id, minute, type, team_id, player_id, player, related_player_id, related_player, weeks, extra)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			matchID, e.Minute, e.Type, e.TeamID, nullableID(e.PlayerID), e.Player, nullableID(e.RelatedPlayerID), e.RelatedPlayer, e.Weeks, e.Extra)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullableID stores a zero player ID as NULL
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// TeamEventStats counts one team's goals by half and its cards
+type TeamEventStats struct {
+	TeamID              int
+	FirstHalfGoals      int
+	SecondHalfGoals     int
+	YellowCards         int
+	RedCards            int
+	LateWinners         int
+	LateWinnersConceded int
+}
+
+// LateWinner is a winning goal scored at or after the late minute
+type LateWinner struct {
+	MatchID int
+	TeamID  int
+	Minute  int
+}
+
+// EventStats summarises the events of a set of matches
+type EventStats struct {
+	FirstHalfGoals  int
+	SecondHalfGoals int
+	Teams           []TeamEventStats
+	LateWinners     []LateWinner
+}
+
+// ComputeEventStats splits goals by half and finds late winners, the goal
+// that decided a match when it came at or after lateMinute. Only completed
+// matches count; goals without events are not included.
+func ComputeEventStats(teams []Team, matches []Match, events []MatchEvent, lateMinute int) EventStats {
+	byTeam := make(map[int]*TeamEventStats)
+	for _, t := range teams {
+		byTeam[t.ID] = &TeamEventStats{TeamID: t.ID}
+	}
+	byMatch := make(map[int][]MatchEvent)
+	for _, e := range events {
+		byMatch[e.MatchID] = append(byMatch[e.MatchID], e)
+	}
+	var stats EventStats
+	for _, m := range matches {
+		if m.Status != StatusCompleted {
+			continue
+		}
+		timeline := byMatch[m.ID]
+		sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].Minute < timeline[j].Minute })
+		var goals []MatchEvent
+		for _, e := range timeline {
+			ts, ok := byTeam[e.TeamID]
+			if !ok {
+				continue
+			}
+			switch e.Type {
+			case EventGoal:
+				goals = append(goals, e)
+				if e.Minute <= MatchMinutes/2 {
+					ts.FirstHalfGoals++
+					stats.FirstHalfGoals++
+				} else {
+					ts.SecondHalfGoals++
+					stats.SecondHalfGoals++
+				}
+			case EventYellowCard:
+				ts.YellowCards++
+			case EventRedCard:
+				ts.RedCards++
+			}
+		}
+		if winner, ok := winningGoal(m, goals); ok && winner.Minute >= lateMinute {
+			stats.LateWinners = append(stats.LateWinners, LateWinner{MatchID: m.ID, TeamID: winner.TeamID, Minute: winner.Minute})
+			byTeam[winner.TeamID].LateWinners++
+			loser := m.HomeTeamID
+			if winner.TeamID == m.HomeTeamID {
+				loser = m.AwayTeamID
+			}
+			if ts, ok := byTeam[loser]; ok {
+				ts.LateWinnersConceded++
+			}
+		}
+	}
+	for _, t := range teams {
+		stats.Teams = append(stats.Teams, *byTeam[t.ID])
+	}
+	return stats
+}
+
+// winningGoal returns the goal that put the winner ahead for good: the
+// winner's goal number loser's goals + 1
+func winningGoal(m Match, goals []MatchEvent) (MatchEvent, bool) {
+	hg, ag := int(m.HomeGoals.Int64), int(m.AwayGoals.Int64)
+	if hg == ag {
+		return MatchEvent{}, false
+	}
+	winner, needed := m.HomeTeamID, ag+1
+	if ag > hg {
+		winner, needed = m.AwayTeamID, hg+1
+	}
+	count := 0
+	for _, g := range goals {
+		if g.TeamID == winner {
+			count++
+			if count == needed {
+				return g, true
+			}
+		}
+	}
+	return MatchEvent{}, false
+}
@@ -0,0 +1,77 @@
+package models
+
+// byeTeamID marks the empty slot added when a league has an odd number of teams
+const byeTeamID = 0
+
+// GenerateDoubleRoundRobin builds a balanced home-and-away schedule for the
+// given teams using the circle method. Every team meets every other team once
+// at home and once away; with an odd team count each round one team sits out.
+// Returned matches have no ID so the database can assign one.
+func GenerateDoubleRoundRobin(teams []Team) []Match {
+	if len(teams) < 2 {
+		return nil
+	}
+	ids := make([]int, 0, len(teams)+1)
+	for _, t := range teams {
+		ids = append(ids, t.ID)
+	}
+	if len(ids)%2 == 1 {
+		ids = append(ids, byeTeamID)
+	}
+	n := len(ids)
+	rounds := n - 1
+	fixed := ids[n-1]
+	rotating := ids[:n-1]
+
+	var firstHalf [][2]int
+	var weeks []int
+	for round := 0; round < rounds; round++ {
+		// Stepping the rotation by n/2 each round (Berger tables) makes
+		// most teams alternate between home and away from week to week.
+		offset := (round * n / 2) % rounds
+		at := func(i int) int {
+			return rotating[(offset+i)%rounds]
+		}
+		// The fixed team swaps venue every round against the team at offset 0
+		home, away := at(0), fixed
+		if round%2 == 1 {
+			home, away = away, home
+		}
+		pairs := [][2]int{{home, away}}
+		for i := 1; i < n/2; i++ {
+			pairs = append(pairs, [2]int{at(i), at(rounds - i)})
+		}
+		for _, p := range pairs {
+			if p[0] == byeTeamID || p[1] == byeTeamID {
+				continue
+			}
+			firstHalf = append(firstHalf, p)
+			weeks = append(weeks, round+1)
+		}
+	}
+
+	matches := make([]Match, 0, 2*len(firstHalf))
+	for i, p := range firstHalf {
+		matches = append(matches, Match{HomeTeamID: p[0], AwayTeamID: p[1], Week: weeks[i], Status: StatusScheduled})
+	}
+	// Second half mirrors the first with venues reversed
+	for i, p := range firstHalf {
+		matches = append(matches, Match{HomeTeamID: p[1], AwayTeamID: p[0], Week: weeks[i] + rounds, Status: StatusScheduled})
+	}
+	return matches
+}
+
+// FixtureClash returns a match of the same week that involves either team of
+// m, other than m itself, since a team can only play once a week
+func FixtureClash(matches []Match, m Match) (Match, bool) {
+	for _, other := range matches {
+		if other.ID == m.ID || other.Week != m.Week {
+			continue
+		}
+		if other.HomeTeamID == m.HomeTeamID || other.HomeTeamID == m.AwayTeamID ||
+			other.AwayTeamID == m.HomeTeamID || other.AwayTeamID == m.AwayTeamID {
+			return other, true
+		}
+	}
+	return Match{}, false
+}
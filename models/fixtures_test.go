@@ -0,0 +1,60 @@
+package models
+
+import "testing"
+
+func TestGenerateDoubleRoundRobin(t *testing.T) {
+	tests := []struct {
+		name  string
+		teams int
+		weeks int
+	}{
+		{"two teams", 2, 2},
+		{"three teams", 3, 6},
+		{"four teams", 4, 6},
+		{"five teams", 5, 10},
+		{"six teams", 6, 10},
+		{"nineteen teams", 19, 38},
+		{"twenty teams", 20, 38},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teams := make([]Team, tt.teams)
+			for i := range teams {
+				teams[i] = Team{ID: i + 1}
+			}
+			matches := GenerateDoubleRoundRobin(teams)
+			if want := tt.teams * (tt.teams - 1); len(matches) != want {
+				t.Fatalf("%d matches, want %d", len(matches), want)
+			}
+			// Every ordered pair is one team hosting the other
+			venues := make(map[[2]int]int)
+			playing := make(map[[2]int]bool)
+			maxWeek := 0
+			for _, m := range matches {
+				if m.HomeTeamID == m.AwayTeamID {
+					t.Fatalf("team %d plays itself in week %d", m.HomeTeamID, m.Week)
+				}
+				venues[[2]int{m.HomeTeamID, m.AwayTeamID}]++
+				for _, id := range []int{m.HomeTeamID, m.AwayTeamID} {
+					if playing[[2]int{m.Week, id}] {
+						t.Errorf("team %d plays twice in week %d", id, m.Week)
+					}
+					playing[[2]int{m.Week, id}] = true
+				}
+				if m.Week > maxWeek {
+					maxWeek = m.Week
+				}
+			}
+			for home := 1; home <= tt.teams; home++ {
+				for away := 1; away <= tt.teams; away++ {
+					if home != away && venues[[2]int{home, away}] != 1 {
+						t.Errorf("team %d hosts team %d %d times, want once", home, away, venues[[2]int{home, away}])
+					}
+				}
+			}
+			if maxWeek != tt.weeks {
+				t.Errorf("schedule runs to week %d, want %d", maxWeek, tt.weeks)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Zone is a named range of finishing positions, such as the title or the
+// relegation places
+type Zone struct {
+	Name string
+	From int
+	To   int
+}
+
+// DefaultZones covers the title and the bottom place of a league
+func DefaultZones(teams int) []Zone {
+	return []Zone{{Name: "title", From: 1, To: 1}, {Name: "relegation", From: teams, To: teams}}
+}
+
+// ParseZones reads a comma separated list such as
+// "title:1,europe:2-3,relegation:4" for a league of the given size
+func ParseZones(s string, teams int) ([]Zone, error) {
+	var zones []Zone
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, positions, ok := strings.Cut(part, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("zone %q must look like name:from-to", part)
+		}
+		fromStr, toStr, isRange := strings.Cut(positions, "-")
+		if !isRange {
+			toStr = fromStr
+		}
+		from, err := strconv.Atoi(strings.TrimSpace(fromStr))
+		if err != nil {
+			return nil, fmt.Errorf("zone %q has an invalid position", part)
+		}
+		to, err := strconv.Atoi(strings.TrimSpace(toStr))
+		if err != nil {
+			return nil, fmt.Errorf("zone %q has an invalid position", part)
+		}
+		if from < 1 || to < from || to > teams {
+			return nil, fmt.Errorf("zone %q must lie between positions 1 and %d", part, teams)
+		}
+		zones = append(zones, Zone{Name: name, From: from, To: to})
+	}
+	return zones, nil
+}
+
+// TeamForecast summarises one team's simulated finishes
+type TeamForecast struct {
+	TeamID   int
+	TeamName string
+	// Positions holds the percentage of runs finishing in each position,
+	// first place first
+	Positions      []float64
+	ExpectedPoints float64
+	// PointsLower and PointsUpper bound the central 95% of simulated totals
+	PointsLower int
+	PointsUpper int
+	Zones       map[string]float64
+}
+
+// Forecast collects the final tables of many simulated seasons
+type Forecast struct {
+	teams    []Team
+	finishes map[int][]int
+	points   map[int][]int
+	runs     int
+}
+
+// NewForecast starts an empty forecast for the given teams
+func NewForecast(teams []Team) *Forecast {
+	f := &Forecast{teams: teams, finishes: make(map[int][]int), points: make(map[int][]int)}
+	for _, t := range teams {
+		f.finishes[t.ID] = make([]int, len(teams))
+	}
+	return f
+}
+
+// Add records one simulated final table
+func (f *Forecast) Add(table []LeagueTableEntry) {
+	for pos, e := range table {
+		if counts, ok := f.finishes[e.TeamID]; ok && pos < len(counts) {
+			counts[pos]++
+			f.points[e.TeamID] = append(f.points[e.TeamID], e.Points)
+		}
+	}
+	f.runs++
+}
+
+// merge adds the runs recorded by another forecast for the same teams
+func (f *Forecast) merge(other *Forecast) {
+	for id, counts := range other.finishes {
+		for pos, count := range counts {
+			f.finishes[id][pos] += count
+		}
+		f.points[id] = append(f.points[id], other.points[id]...)
+	}
+	f.runs += other.runs
+}
+
+// Runs returns how many tables have been recorded
+func (f *Forecast) Runs() int {
+	return f.runs
+}
+
+// Summary returns every team's forecast, most expected points first
+func (f *Forecast) Summary(zones []Zone) []TeamForecast {
+	var result []TeamForecast
+	for _, t := range f.teams {
+		tf := TeamForecast{TeamID: t.ID, TeamName: t.Name, Positions: make([]float64, len(f.teams)), Zones: make(map[string]float64)}
+		if f.runs > 0 {
+			for pos, count := range f.finishes[t.ID] {
+				tf.Positions[pos] = float64(count) * 100.0 / float64(f.runs)
+			}
+			for _, z := range zones {
+				for pos := z.From; pos <= z.To; pos++ {
+					tf.Zones[z.Name] += tf.Positions[pos-1]
+				}
+			}
+			points := append([]int(nil), f.points[t.ID]...)
+			sort.Ints(points)
+			total := 0
+			for _, p := range points {
+				total += p
+			}
+			tf.ExpectedPoints = float64(total) / float64(len(points))
+			tf.PointsLower = percentile(points, 0.025)
+			tf.PointsUpper = percentile(points, 0.975)
+		}
+		result = append(result, tf)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].ExpectedPoints != result[j].ExpectedPoints {
+			return result[i].ExpectedPoints > result[j].ExpectedPoints
+		}
+		return result[i].TeamID < result[j].TeamID
+	})
+	return result
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []int, p float64) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
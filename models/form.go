@@ -0,0 +1,195 @@
+package models
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// LeagueSimulator is a MatchSimulator whose results depend on the state of
+// the league, such as recent form. ForLeague returns a simulator bound to
+// the league as it stands.
+type LeagueSimulator interface {
+	MatchSimulator
+	ForLeague(league League) MatchSimulator
+}
+
+// BindSimulator binds sim to the league when it depends on it and returns
+// it unchanged otherwise
+func BindSimulator(sim MatchSimulator, league League) MatchSimulator {
+	if ls, ok := sim.(LeagueSimulator); ok {
+		return ls.ForLeague(league)
+	}
+	return sim
+}
+
+// FormResult is one of a team's recent results
+type FormResult struct {
+	Match        Match
+	GoalsFor     int
+	GoalsAgainst int
+	// Outcome is W, D or L
+	Outcome string
+	Points  int
+}
+
+// TeamForm is a team's record over its last few played matches, oldest
+// first
+type TeamForm struct {
+	TeamID  int
+	Results []FormResult
+	Points  int
+}
+
+// String returns the results as a W/D/L string, oldest first
+func (f TeamForm) String() string {
+	s := ""
+	for _, r := range f.Results {
+		s += r.Outcome
+	}
+	return s
+}
+
+// PointsPerGame returns the average points over the form window
+func (f TeamForm) PointsPerGame() float64 {
+	if len(f.Results) == 0 {
+		return 0
+	}
+	return float64(f.Points) / float64(len(f.Results))
+}
+
+// Score places the form on a scale from -1 (lost every match) to 1 (won
+// every match), with zero halfway between the win and loss points. Teams
+// without results score zero.
+func (f TeamForm) Score(scoring ScoringRules) float64 {
+	span := float64(scoring.WinPoints-scoring.LossPoints) / 2
+	if len(f.Results) == 0 || span == 0 {
+		return 0
+	}
+	mid := float64(scoring.WinPoints+scoring.LossPoints) / 2
+	return (f.PointsPerGame() - mid) / span
+}
+
+// teamResults returns a team's counting results in the order they were
+// played. Only the result counts towards form, not bonus points.
+func teamResults(matches []Match, teamID int, scoring ScoringRules) []FormResult {
+	var results []FormResult
+	for _, m := range matches {
+		if !m.Status.CountsInTable() || (m.HomeTeamID != teamID && m.AwayTeamID != teamID) {
+			continue
+		}
+		r := FormResult{Match: m, GoalsFor: int(m.HomeGoals.Int64), GoalsAgainst: int(m.AwayGoals.Int64)}
+		if m.AwayTeamID == teamID {
+			r.GoalsFor, r.GoalsAgainst = r.GoalsAgainst, r.GoalsFor
+		}
+		switch {
+		case r.GoalsFor > r.GoalsAgainst:
+			r.Outcome = "W"
+		case r.GoalsFor < r.GoalsAgainst:
+			r.Outcome = "L"
+		default:
+			r.Outcome = "D"
+		}
+		r.Points = scoring.ResultPoints(r.GoalsFor, r.GoalsAgainst)
+		results = append(results, r)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Match.Week != results[j].Match.Week {
+			return results[i].Match.Week < results[j].Match.Week
+		}
+		return results[i].Match.ID < results[j].Match.ID
+	})
+	return results
+}
+
+// CalculateForm returns a team's form over its last window played matches
+func CalculateForm(matches []Match, teamID int, window int, scoring ScoringRules) TeamForm {
+	results := teamResults(matches, teamID, scoring)
+	if window > 0 && len(results) > window {
+		results = results[len(results)-window:]
+	}
+	form := TeamForm{TeamID: teamID, Results: results}
+	for _, r := range results {
+		form.Points += r.Points
+	}
+	return form
+}
+
+// RollingPointsPerGame returns, after each of a team's played matches, the
+// points per game over the window ending with it
+func RollingPointsPerGame(matches []Match, teamID int, window int, scoring ScoringRules) []float64 {
+	results := teamResults(matches, teamID, scoring)
+	rolling := make([]float64, len(results))
+	sum := 0
+	for i, r := range results {
+		sum += r.Points
+		n := i + 1
+		if window > 0 && n > window {
+			sum -= results[i-window].Points
+			n = window
+		}
+		rolling[i] = float64(sum) / float64(n)
+	}
+	return rolling
+}
+
+// FormMatchSimulator wraps another simulator and scales each side's
+// strength by its recent form: a team that won its last Window matches
+// plays at 1+Weight times its strength and one that lost them all at
+// 1-Weight. It has to be bound to a league with ForLeague; unbound it
+// plays like the wrapped simulator.
+type FormMatchSimulator struct {
+	Simulator MatchSimulator
+	// Window is the number of recent matches that make up form
+	Window int
+	// Weight is the largest share of strength form can add or take away
+	Weight  float64
+	factors map[int]float64
+}
+
+// Form defaults
+const (
+	DefaultFormWindow = 5
+	DefaultFormWeight = 0.1
+)
+
+// NewFormMatchSimulator wraps sim with the default window and weight
+func NewFormMatchSimulator(sim MatchSimulator) FormMatchSimulator {
+	return FormMatchSimulator{Simulator: sim, Window: DefaultFormWindow, Weight: DefaultFormWeight}
+}
+
+// ForLeague works out every team's form from the league's played matches
+func (f FormMatchSimulator) ForLeague(league League) MatchSimulator {
+	scoring := league.Scoring
+	if scoring == (ScoringRules{}) {
+		scoring = DefaultScoringRules
+	}
+	f.Simulator = BindSimulator(f.Simulator, league)
+	f.factors = make(map[int]float64)
+	for _, t := range league.Teams {
+		f.factors[t.ID] = 1 + f.Weight*CalculateForm(league.Matches, t.ID, f.Window, scoring).Score(scoring)
+	}
+	return f
+}
+
+// withForm returns the team with its strength factor scaled by its form
+func (f FormMatchSimulator) withForm(t Team) Team {
+	factor, ok := f.factors[t.ID]
+	if !ok {
+		return t
+	}
+	if t.StrengthFactor > 0 {
+		factor *= t.StrengthFactor
+	}
+	t.StrengthFactor = factor
+	return t
+}
+
+// SimulateMatch returns simulated goals for home and away teams
+func (f FormMatchSimulator) SimulateMatch(home Team, away Team) (int, int) {
+	return f.SimulateMatchWithRand(home, away, newRand())
+}
+
+// SimulateMatchWithRand is SimulateMatch drawing from the given generator
+func (f FormMatchSimulator) SimulateMatchWithRand(home Team, away Team, rng *rand.Rand) (int, int) {
+	return f.Simulator.SimulateMatchWithRand(f.withForm(home), f.withForm(away), rng)
+}
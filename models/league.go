@@ -2,7 +2,7 @@ package models
 
 import (
 	"Case_study/storage"
-	"sort"
+	"database/sql"
 )
 
 // LeagueTableEntry represents a row in the league table
@@ -14,57 +14,272 @@ import (
     GoalsAgainst   int
     GoalDifference int
     MatchesPlayed  int
+    Wins           int
+    Draws          int
+    Losses         int
+    AwayGoalsFor   int
+    PointsDeducted int
 }
 
 // League represents the league state
  type League struct {
+    ID       int
+    Name     string
+    SeasonID int
     Teams   []Team
     Matches []Match
     Week    int
+    // TieBreakers orders teams level on points; LotsSeed seeds the
+    // drawing of lots when that criterion is used
+    TieBreakers []TieBreaker
+    LotsSeed    int64
+    // Scoring awards points per result; Deductions are sanctions in the
+    // current season
+    Scoring    ScoringRules
+    Deductions []PointDeduction
+    // Absences are the players ruled out this season; GetLeague leaves it
+    // empty and the Monte Carlo engine uses it when set
+    Absences []Absence
+    // RatingHistory is the season's recalculated rating trajectory; it is
+    // only written by UpdateLeague when set
+    RatingHistory []RatingPoint
 }
 
 // LeagueRepository defines DB operations for the league
  type LeagueRepository interface {
-    GetLeague() (League, error)
+    GetLeague(leagueID int) (League, error)
+    GetAllLeagues() ([]League, error)
+    CreateLeague(name string) (int, error)
+    SetupLeague(name string, teams []Team, seasonName string) (int, error)
+    UpdateTieBreakers(leagueID int, order []TieBreaker, lotsSeed int64) error
+    GetScoringRules(leagueID int) (ScoringRules, error)
+    UpdateScoringRules(leagueID int, rules ScoringRules) error
+    GetDeductions(seasonID int) ([]PointDeduction, error)
+    CreateDeduction(d PointDeduction) (int, error)
+    DeleteDeduction(seasonID int, id int) error
     UpdateLeague(league League) error
 }
 
 // SQLiteLeagueRepository implements LeagueRepository using SQLite
  type SQLiteLeagueRepository struct{}
 
-func (r SQLiteLeagueRepository) GetLeague() (League, error) {
+// GetLeague loads a league with its teams and the matches of its current season
+func (r SQLiteLeagueRepository) GetLeague(leagueID int) (League, error) {
 	db := storage.GetDB()
+	var err error
 	var league League
+	var tieBreakers string
+	row := db.QueryRow("SELECT id, name, tie_breakers, lots_seed FROM leagues WHERE id = ?", leagueID)
+	if err := row.Scan(&league.ID, &league.Name, &tieBreakers, &league.LotsSeed); err != nil {
+		if err == sql.ErrNoRows {
+			return league, ErrNotFound
+		}
+		return league, err
+	}
+	league.TieBreakers, err = ParseTieBreakers(tieBreakers)
+	if err != nil {
+		return league, err
+	}
+	league.Scoring, err = r.GetScoringRules(leagueID)
+	if err != nil {
+		return league, err
+	}
 	// Get teams
 	teamRepo := SQLiteTeamRepository{}
-	teams, err := teamRepo.GetAllTeams()
+	league.Teams, err = teamRepo.GetTeamsByLeague(leagueID)
 	if err != nil {
 		return league, err
 	}
-	league.Teams = teams
-	// Get matches
-	rows, err := db.Query("SELECT id, home_team_id, away_team_id, home_goals, away_goals, week, played FROM matches")
+	players, err := SQLitePlayerRepository{}.GetPlayersByLeague(leagueID)
 	if err != nil {
 		return league, err
 	}
+	for i := range league.Teams {
+		for _, p := range players {
+			if p.TeamID == league.Teams[i].ID {
+				league.Teams[i].Players = append(league.Teams[i].Players, p)
+			}
+		}
+	}
+	// Get matches of the season in progress
+	seasonRepo := SQLiteSeasonRepository{}
+	season, err := seasonRepo.GetCurrentSeason(leagueID)
+	if err != nil {
+		return league, err
+	}
+	league.SeasonID = season.ID
+	league.Deductions, err = r.GetDeductions(season.ID)
+	if err != nil {
+		return league, err
+	}
+	matchRepo := SQLiteMatchRepository{}
+	league.Matches, err = matchRepo.GetMatchesBySeason(season.ID)
+	if err != nil {
+		return league, err
+	}
+	return league, nil
+}
+
+// GetAllLeagues returns every league without loading teams or matches
+func (r SQLiteLeagueRepository) GetAllLeagues() ([]League, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT id, name FROM leagues ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
+	var leagues []League
 	for rows.Next() {
-		var m Match
-		if err := rows.Scan(&m.ID, &m.HomeTeamID, &m.AwayTeamID, &m.HomeGoals, &m.AwayGoals, &m.Week, &m.Played); err != nil {
-			return league, err
+		var l League
+		if err := rows.Scan(&l.ID, &l.Name); err != nil {
+			return nil, err
 		}
-		league.Matches = append(league.Matches, m)
+		leagues = append(leagues, l)
 	}
-	return league, nil
+	return leagues, rows.Err()
 }
 
+// CreateLeague adds an empty league and returns its ID
+func (r SQLiteLeagueRepository) CreateLeague(name string) (int, error) {
+	return createLeagueTx(storage.GetDB(), name)
+}
+
+func createLeagueTx(tx storage.DBTX, name string) (int, error) {
+	res, err := tx.Exec("INSERT INTO leagues (name, tie_breakers) VALUES (?, ?)", name, FormatTieBreakers(DefaultTieBreakers))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// SetupLeague creates a league with its teams and a first season with a
+// double round-robin, empty standings and the teams' starting ratings, all
+// in one transaction so a failure leaves nothing behind. It returns the
+// new league's ID.
+func (r SQLiteLeagueRepository) SetupLeague(name string, teams []Team, seasonName string) (int, error) {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	league := League{Name: name, TieBreakers: DefaultTieBreakers}
+	if league.ID, err = createLeagueTx(tx, name); err != nil {
+		return 0, err
+	}
+	var start []RatingPoint
+	for _, t := range teams {
+		t.LeagueID = league.ID
+		if t.ID, err = createTeamTx(tx, t); err != nil {
+			return 0, err
+		}
+		t.Rating = InitialRating(t.Strength)
+		start = append(start, RatingPoint{TeamID: t.ID, Week: 0, Rating: t.Rating})
+		league.Teams = append(league.Teams, t)
+	}
+	if league.SeasonID, err = createSeasonTx(tx, league.ID, seasonName); err != nil {
+		return 0, err
+	}
+	if err := replaceSeasonMatchesTx(tx, league.SeasonID, GenerateDoubleRoundRobin(league.Teams)); err != nil {
+		return 0, err
+	}
+	if err := writeStandings(tx, league.SeasonID, league.CalculateTable()); err != nil {
+		return 0, err
+	}
+	if err := saveRatingsTx(tx, league.SeasonID, league.Teams, start); err != nil {
+		return 0, err
+	}
+	return league.ID, tx.Commit()
+}
+
+// UpdateTieBreakers stores a league's tie-breaker chain
+func (r SQLiteLeagueRepository) UpdateTieBreakers(leagueID int, order []TieBreaker, lotsSeed int64) error {
+	db := storage.GetDB()
+	_, err := db.Exec("UPDATE leagues SET tie_breakers = ?, lots_seed = ? WHERE id = ?", FormatTieBreakers(order), lotsSeed, leagueID)
+	return err
+}
+
+// UpdateLeague persists the league's teams, the fixtures and results of its
+// current season, the recalculated standings and, when present, the season's rating
+// history in a single transaction, so a failure leaves nothing half written.
+// It returns ErrInvalidTransition if a match status change is not allowed.
 func (r SQLiteLeagueRepository) UpdateLeague(league League) error {
-	// Not implemented: would update all teams and matches
-	return nil
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, t := range league.Teams {
+		_, err := tx.Exec("UPDATE teams SET name = ?, strength = ?, rating = ?, fair_play_points = ? WHERE id = ? AND league_id = ?",
+			t.Name, t.Strength, nullableRating(t.Rating), t.FairPlayPoints, t.ID, league.ID)
+		if err != nil {
+			return err
+		}
+	}
+	rows, err := tx.Query("SELECT "+matchColumns+" FROM matches WHERE season_id = ?", league.SeasonID)
+	if err != nil {
+		return err
+	}
+	stored, err := scanMatches(rows)
+	if err != nil {
+		return err
+	}
+	statuses := make(map[int]MatchStatus)
+	current := make(map[int]Match)
+	for _, m := range stored {
+		statuses[m.ID] = m.Status
+		current[m.ID] = m
+	}
+	// A live match belongs to its stream, which stores its score as it
+	// goes and may have finished it since the league was loaded, so it is
+	// left as stored and the table uses the stored row
+	matches := make([]Match, len(league.Matches))
+	copy(matches, league.Matches)
+	for i, m := range matches {
+		if m.Status == StatusLive {
+			if s, ok := current[m.ID]; ok {
+				matches[i] = s
+			}
+		}
+	}
+	for _, m := range league.Matches {
+		if m.Status == StatusLive {
+			continue
+		}
+		if old, ok := statuses[m.ID]; ok {
+			if err := checkTransition(m.ID, old, m.Status); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("UPDATE matches SET home_team_id = ?, away_team_id = ?, week = ?, home_goals = ?, away_goals = ?, played = ?, status = ? WHERE id = ? AND season_id = ?",
+			m.HomeTeamID, m.AwayTeamID, m.Week, nullableInt(m.HomeGoals), nullableInt(m.AwayGoals), m.Status.CountsInTable(), m.Status, m.ID, league.SeasonID)
+		if err != nil {
+			return err
+		}
+		if m.Events != nil {
+			if err := writeMatchEvents(tx, m.ID, m.Events); err != nil {
+				return err
+			}
+		}
+	}
+	league.Matches = matches
+	if err := writeStandings(tx, league.SeasonID, league.CalculateTable()); err != nil {
+		return err
+	}
+	if league.RatingHistory != nil {
+		if err := writeRatingHistory(tx, league.SeasonID, league.RatingHistory); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-// CalculateTable updates the league table based on played matches
+// CalculateTable updates the league table based on played matches, the
+// league's scoring rules and any point deductions
 func (l *League) CalculateTable() []LeagueTableEntry {
+    rules := l.ScoringRules()
     // Reset stats
     stats := make(map[int]*LeagueTableEntry)
     for _, t := range l.Teams {
@@ -74,7 +289,7 @@ func (l *League) CalculateTable() []LeagueTableEntry {
         }
     }
     for _, m := range l.Matches {
-        if !m.Played {
+        if !m.Status.CountsInTable() {
             continue
         }
         home := stats[m.HomeTeamID]
@@ -92,14 +307,25 @@ func (l *League) CalculateTable() []LeagueTableEntry {
         home.MatchesPlayed++
         away.GoalsFor += awayGoals
         away.GoalsAgainst += homeGoals
+        away.AwayGoalsFor += awayGoals
         away.MatchesPlayed++
+        home.Points += rules.MatchPoints(homeGoals, awayGoals)
+        away.Points += rules.MatchPoints(awayGoals, homeGoals)
         if homeGoals > awayGoals {
-            home.Points += 3
+            home.Wins++
+            away.Losses++
         } else if homeGoals < awayGoals {
-            away.Points += 3
+            away.Wins++
+            home.Losses++
         } else {
-            home.Points++
-            away.Points++
+            home.Draws++
+            away.Draws++
+        }
+    }
+    for _, d := range l.Deductions {
+        if entry, ok := stats[d.TeamID]; ok {
+            entry.Points -= d.Points
+            entry.PointsDeducted += d.Points
         }
     }
     // Calculate goal difference
@@ -108,15 +334,34 @@ func (l *League) CalculateTable() []LeagueTableEntry {
         entry.GoalDifference = entry.GoalsFor - entry.GoalsAgainst
         table = append(table, *entry)
     }
-    // Sort by points, then goal difference, then goals for
-    sort.Slice(table, func(i, j int) bool {
-        if table[i].Points != table[j].Points {
-            return table[i].Points > table[j].Points
-        }
-        if table[i].GoalDifference != table[j].GoalDifference {
-            return table[i].GoalDifference > table[j].GoalDifference
-        }
-        return table[i].GoalsFor > table[j].GoalsFor
-    })
-    return table
-} 
\ No newline at end of file
+    return l.RankEntries(table)
+}
+
+// RankEntries orders table rows, computed or stored, with the league's
+// tie-breakers
+func (l *League) RankEntries(table []LeagueTableEntry) []LeagueTableEntry {
+    return RankTable(table, l.Matches, l.TieBreakRules())
+}
+
+// TieBreakRules collects the league's tie-breaker configuration together
+// with each team's fair-play record
+func (l *League) TieBreakRules() TieBreakRules {
+    order := l.TieBreakers
+    if len(order) == 0 {
+        order = DefaultTieBreakers
+    }
+    fairPlay := make(map[int]int)
+    for _, t := range l.Teams {
+        fairPlay[t.ID] = t.FairPlayPoints
+    }
+    return TieBreakRules{Order: order, LotsSeed: l.LotsSeed, FairPlay: fairPlay, Scoring: l.ScoringRules()}
+}
+
+// ScoringRules returns the league's rules, falling back to 3/1/0 when none
+// have been loaded
+func (l *League) ScoringRules() ScoringRules {
+    if l.Scoring == (ScoringRules{}) {
+        return DefaultScoringRules
+    }
+    return l.Scoring
+}
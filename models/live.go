@@ -0,0 +1,299 @@
+package models
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// MatchMinutes is the length of a match without stoppage time
+const MatchMinutes = 90
+
+// EventType names something that happens during a match
+type EventType string
+
+const (
+	EventKickOff      EventType = "kick_off"
+	EventLineup       EventType = "lineup"
+	EventGoal         EventType = "goal"
+	EventYellowCard   EventType = "yellow_card"
+	EventRedCard      EventType = "red_card"
+	EventSubstitution EventType = "substitution"
+	EventInjury       EventType = "injury"
+	EventHalfTime     EventType = "half_time"
+	EventFullTime     EventType = "full_time"
+)
+
+// LiveEvent is one moment of a simulated match together with the score
+// after it
+type LiveEvent struct {
+	Minute int
+	Type   EventType
+	// TeamID is zero for events that belong to neither side
+	TeamID int
+	// PlayerID and Player name the squad member involved, when the team
+	// has a squad: the scorer, the player booked or injured, the starter
+	// or the player substituted
+	PlayerID int
+	Player   string
+	// RelatedPlayerID and RelatedPlayer are the goal's assist or the
+	// substitute coming on
+	RelatedPlayerID int
+	RelatedPlayer   string
+	// Weeks is how long an injury rules the player out
+	Weeks     int
+	HomeGoals int
+	AwayGoals int
+}
+
+// eventOrder places kick-off first and the whistles after everything else
+// that happened in the same minute
+func eventOrder(t EventType) int {
+	switch t {
+	case EventKickOff:
+		return 0
+	case EventHalfTime:
+		return 2
+	case EventFullTime:
+		return 3
+	}
+	return 1
+}
+
+// SimulateLive plays a match minute by minute. The final score comes from
+// sim, so live matches follow the same distribution as instant ones; goals,
+// cards, injuries and substitutions are then spread over the 90 minutes
+// and given to players of the starting lineups. Only teams with a squad
+// pick up injuries.
+func SimulateLive(home Team, away Team, sim MatchSimulator, rng *rand.Rand) []LiveEvent {
+	homeGoals, awayGoals := sim.SimulateMatchWithRand(home, away, rng)
+	events := []LiveEvent{
+		{Minute: 0, Type: EventKickOff},
+		{Minute: MatchMinutes / 2, Type: EventHalfTime},
+		{Minute: MatchMinutes, Type: EventFullTime},
+	}
+	for _, side := range []struct {
+		teamID int
+		goals  int
+		squad  bool
+	}{{home.ID, homeGoals, len(home.Players) > 0}, {away.ID, awayGoals, len(away.Players) > 0}} {
+		for i := 0; i < side.goals; i++ {
+			events = append(events, LiveEvent{Minute: 1 + rng.Intn(MatchMinutes), Type: EventGoal, TeamID: side.teamID})
+		}
+		for i := samplePoisson(1.7, rng); i > 0; i-- {
+			events = append(events, LiveEvent{Minute: 1 + rng.Intn(MatchMinutes), Type: EventYellowCard, TeamID: side.teamID})
+		}
+		if rng.Intn(15) == 0 {
+			events = append(events, LiveEvent{Minute: 1 + rng.Intn(MatchMinutes), Type: EventRedCard, TeamID: side.teamID})
+		}
+		if side.squad && rng.Float64() < injuryChance {
+			events = append(events, LiveEvent{Minute: 1 + rng.Intn(MatchMinutes), Type: EventInjury, TeamID: side.teamID, Weeks: 1 + rng.Intn(maxInjuryWeeks)})
+		}
+		// Substitutions come in the second half
+		for i := 0; i < 3; i++ {
+			events = append(events, LiveEvent{Minute: MatchMinutes/2 + 1 + rng.Intn(MatchMinutes/2), Type: EventSubstitution, TeamID: side.teamID})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Minute != events[j].Minute {
+			return events[i].Minute < events[j].Minute
+		}
+		return eventOrder(events[i].Type) < eventOrder(events[j].Type)
+	})
+	hg, ag := 0, 0
+	for i := range events {
+		if events[i].Type == EventGoal {
+			if events[i].TeamID == home.ID {
+				hg++
+			} else {
+				ag++
+			}
+		}
+		events[i].HomeGoals, events[i].AwayGoals = hg, ag
+	}
+	return assignPlayers(events, home, away, rng)
+}
+
+// side tracks who is on the pitch and who is left on the bench
+type side struct {
+	onPitch []Player
+	bench   []Player
+	cameOn  map[int]bool
+}
+
+// injuryChance is how often a side loses a player to injury in a match,
+// for between one and maxInjuryWeeks weeks
+const (
+	injuryChance   = 0.12
+	maxInjuryWeeks = 4
+)
+
+// assistChance is how often a goal has an assist
+const assistChance = 0.75
+
+// assistWeight favours midfielders as providers
+var assistWeight = map[Position]float64{PositionDefender: 1, PositionMidfielder: 3, PositionForward: 2}
+
+// outfield gives every outfield player the same weight
+func outfield(p Player) float64 {
+	if p.Position == PositionGoalkeeper {
+		return 0
+	}
+	return 1
+}
+
+// assignPlayers puts the starting lineups on the timeline after kick-off
+// and names the players involved in every event. Goals favour the players
+// with the best attack, forwards most of all, and most have an assist;
+// cards go to any outfield player; substitutes replace outfield players,
+// preferably in the same position, and are not taken off again. An
+// injured player is replaced at once. A player sent off, injured or
+// substituted takes no further part. Teams without a full squad are left as they are.
+func assignPlayers(events []LiveEvent, home Team, away Team, rng *rand.Rand) []LiveEvent {
+	sides := make(map[int]*side)
+	for _, t := range []Team{home, away} {
+		eleven := StartingEleven(t.Players)
+		if len(eleven) < ElevenSize {
+			continue
+		}
+		starting := make(map[int]bool)
+		for _, p := range eleven {
+			starting[p.ID] = true
+		}
+		s := &side{onPitch: eleven, cameOn: make(map[int]bool)}
+		for _, p := range t.Players {
+			if !starting[p.ID] {
+				s.bench = append(s.bench, p)
+			}
+		}
+		sides[t.ID] = s
+	}
+	var result []LiveEvent
+	for _, e := range events {
+		if s, ok := sides[e.TeamID]; ok {
+			e = s.attribute(e, rng)
+		}
+		result = append(result, e)
+		if e.Type != EventKickOff {
+			continue
+		}
+		for _, t := range []Team{home, away} {
+			if s, ok := sides[t.ID]; ok {
+				for _, p := range s.onPitch {
+					result = append(result, LiveEvent{Minute: e.Minute, Type: EventLineup, TeamID: t.ID, PlayerID: p.ID, Player: p.Name})
+				}
+			}
+		}
+	}
+	return result
+}
+
+// attribute names the players involved in one event and updates who is
+// on the pitch
+func (s *side) attribute(e LiveEvent, rng *rand.Rand) LiveEvent {
+	switch e.Type {
+	case EventGoal:
+		i, ok := pickPlayer(s.onPitch, func(p Player) float64 { return attackWeight[p.Position] * float64(p.Attack) }, rng)
+		if !ok {
+			return e
+		}
+		scorer := s.onPitch[i]
+		e.PlayerID, e.Player = scorer.ID, scorer.Name
+		if rng.Float64() < assistChance {
+			weight := func(p Player) float64 {
+				if p.ID == scorer.ID {
+					return 0
+				}
+				return assistWeight[p.Position] * float64(p.Attack)
+			}
+			if j, ok := pickPlayer(s.onPitch, weight, rng); ok {
+				e.RelatedPlayerID, e.RelatedPlayer = s.onPitch[j].ID, s.onPitch[j].Name
+			}
+		}
+	case EventYellowCard, EventRedCard:
+		i, ok := pickPlayer(s.onPitch, outfield, rng)
+		if !ok {
+			return e
+		}
+		e.PlayerID, e.Player = s.onPitch[i].ID, s.onPitch[i].Name
+		if e.Type == EventRedCard {
+			s.onPitch = append(s.onPitch[:i:i], s.onPitch[i+1:]...)
+		}
+	case EventSubstitution:
+		i, ok := pickPlayer(s.onPitch, func(p Player) float64 {
+			if s.cameOn[p.ID] {
+				return 0
+			}
+			return outfield(p)
+		}, rng)
+		if !ok {
+			return e
+		}
+		off := s.onPitch[i]
+		on, ok := s.substitute(i, rng)
+		if !ok {
+			return e
+		}
+		e.PlayerID, e.Player = off.ID, off.Name
+		e.RelatedPlayerID, e.RelatedPlayer = on.ID, on.Name
+	case EventInjury:
+		i, ok := pickPlayer(s.onPitch, func(Player) float64 { return 1 }, rng)
+		if !ok {
+			return e
+		}
+		e.PlayerID, e.Player = s.onPitch[i].ID, s.onPitch[i].Name
+		if on, ok := s.substitute(i, rng); ok {
+			e.RelatedPlayerID, e.RelatedPlayer = on.ID, on.Name
+		} else {
+			// Nobody left on the bench: the side plays on a man short
+			s.onPitch = append(s.onPitch[:i:i], s.onPitch[i+1:]...)
+		}
+	}
+	return e
+}
+
+// substitute replaces the player at index i of the pitch with one from the
+// bench, preferably in the same position. It reports false when nobody
+// suitable is left on the bench.
+func (s *side) substitute(i int, rng *rand.Rand) (Player, bool) {
+	off := s.onPitch[i]
+	j, ok := pickPlayer(s.bench, func(p Player) float64 {
+		if p.Position == off.Position {
+			return 3
+		}
+		return outfield(p)
+	}, rng)
+	if !ok {
+		return Player{}, false
+	}
+	on := s.bench[j]
+	s.onPitch = append(s.onPitch[:i:i], s.onPitch[i+1:]...)
+	s.onPitch = append(s.onPitch, on)
+	s.cameOn[on.ID] = true
+	s.bench = append(s.bench[:j:j], s.bench[j+1:]...)
+	return on, true
+}
+
+// pickPlayer draws the index of a player with probability proportional to
+// weight
+func pickPlayer(players []Player, weight func(Player) float64, rng *rand.Rand) (int, bool) {
+	total := 0.0
+	for _, p := range players {
+		total += weight(p)
+	}
+	if total <= 0 {
+		return 0, false
+	}
+	x := rng.Float64() * total
+	last := 0
+	for i, p := range players {
+		if weight(p) <= 0 {
+			continue
+		}
+		last = i
+		if x -= weight(p); x < 0 {
+			return i, true
+		}
+	}
+	// Rounding can leave x just above zero
+	return last, true
+}
@@ -9,17 +9,33 @@ import (
 // Match represents a football match between two teams
  type Match struct {
     ID         int
+    SeasonID   int
     HomeTeamID int
     AwayTeamID int
     HomeGoals  sql.NullInt64
     AwayGoals  sql.NullInt64
     Week       int
+    // Played is true while the result counts in the table, that is when
+    // Status is completed or awarded; SetStatus keeps the two in step
     Played     bool
+    Status     MatchStatus
+    // Events is the match timeline to store with it; nil leaves the stored
+    // events alone and an empty slice clears them
+    Events     []MatchEvent
 }
 
 // MatchSimulator defines logic for simulating a match
  type MatchSimulator interface {
     SimulateMatch(home Team, away Team) (homeGoals int, awayGoals int)
+    // SimulateMatchWithRand draws only from rng, so a seeded generator
+    // always reproduces the same score
+    SimulateMatchWithRand(home Team, away Team, rng *rand.Rand) (homeGoals int, awayGoals int)
+}
+
+// newRand returns a generator seeded from the package-level source, for
+// simulations that do not need to be reproducible
+func newRand() *rand.Rand {
+    return rand.New(rand.NewSource(rand.Int63()))
 }
 
 // BasicMatchSimulator simulates matches based on team strengths
@@ -27,10 +43,15 @@ import (
 
 // SimulateMatch returns simulated goals for home and away teams
 func (b BasicMatchSimulator) SimulateMatch(home Team, away Team) (int, int) {
+    return b.SimulateMatchWithRand(home, away, newRand())
+}
+
+// SimulateMatchWithRand is SimulateMatch drawing from the given generator
+func (b BasicMatchSimulator) SimulateMatchWithRand(home Team, away Team, rng *rand.Rand) (int, int) {
     // Simple probabilistic model: higher strength = more likely to score
     // Home advantage: +10% strength
-    homeStrength := float64(home.Strength) * 1.1
-    awayStrength := float64(away.Strength)
+    homeStrength := home.CurrentStrength() * 1.1
+    awayStrength := away.CurrentStrength()
     totalStrength := homeStrength + awayStrength
     
     // Expected goals: scale to 0-3 goals per team
@@ -38,10 +59,10 @@ func (b BasicMatchSimulator) SimulateMatch(home Team, away Team) (int, int) {
     awayGoals := int((awayStrength/totalStrength)*3 + 0.5)
     
     // Add some randomness
-    if homeGoals > 0 && rand.Intn(4) == 0 { homeGoals-- }
-    if awayGoals > 0 && rand.Intn(4) == 0 { awayGoals-- }
-    if rand.Intn(10) == 0 { homeGoals++ }
-    if rand.Intn(10) == 0 { awayGoals++ }
+    if homeGoals > 0 && rng.Intn(4) == 0 { homeGoals-- }
+    if awayGoals > 0 && rng.Intn(4) == 0 { awayGoals-- }
+    if rng.Intn(10) == 0 { homeGoals++ }
+    if rng.Intn(10) == 0 { awayGoals++ }
     if homeGoals < 0 { homeGoals = 0 }
     if awayGoals < 0 { awayGoals = 0 }
     return homeGoals, awayGoals
@@ -50,37 +71,214 @@ func (b BasicMatchSimulator) SimulateMatch(home Team, away Team) (int, int) {
 // SQLiteMatchRepository implements DB operations for matches
  type SQLiteMatchRepository struct{}
 
-func (r SQLiteMatchRepository) GetMatchesByWeek(week int) ([]Match, error) {
-	db := storage.GetDB()
-	rows, err := db.Query("SELECT id, home_team_id, away_team_id, home_goals, away_goals, week, played FROM matches WHERE week = ?", week)
-	if err != nil {
-		return nil, err
-	}
+const matchColumns = "id, season_id, home_team_id, away_team_id, home_goals, away_goals, week, status"
+
+func scanMatches(rows *sql.Rows) ([]Match, error) {
 	defer rows.Close()
 	var matches []Match
 	for rows.Next() {
 		var m Match
-		if err := rows.Scan(&m.ID, &m.HomeTeamID, &m.AwayTeamID, &m.HomeGoals, &m.AwayGoals, &m.Week, &m.Played); err != nil {
+		if err := rows.Scan(&m.ID, &m.SeasonID, &m.HomeTeamID, &m.AwayTeamID, &m.HomeGoals, &m.AwayGoals, &m.Week, &m.Status); err != nil {
 			return nil, err
 		}
+		m.Played = m.Status.CountsInTable()
 		matches = append(matches, m)
 	}
-	return matches, nil
+	return matches, rows.Err()
+}
+
+func (r SQLiteMatchRepository) GetMatchesByWeek(seasonID int, week int) ([]Match, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT "+matchColumns+" FROM matches WHERE season_id = ? AND week = ?", seasonID, week)
+	if err != nil {
+		return nil, err
+	}
+	return scanMatches(rows)
 }
 
+// GetMatchByID returns a single match or ErrNotFound
+func (r SQLiteMatchRepository) GetMatchByID(id int) (Match, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT "+matchColumns+" FROM matches WHERE id = ?", id)
+	if err != nil {
+		return Match{}, err
+	}
+	matches, err := scanMatches(rows)
+	if err != nil {
+		return Match{}, err
+	}
+	if len(matches) == 0 {
+		return Match{}, ErrNotFound
+	}
+	return matches[0], nil
+}
+
+// GetMatchesBySeason returns every fixture of a season in schedule order
+func (r SQLiteMatchRepository) GetMatchesBySeason(seasonID int) ([]Match, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT "+matchColumns+" FROM matches WHERE season_id = ? ORDER BY week, id", seasonID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMatches(rows)
+}
+
+// UpdateMatch stores a match's score and status and, in the same
+// transaction, moves the stored standings from the old result to the new
+// one. It returns ErrInvalidTransition if the status change is not allowed.
 func (r SQLiteMatchRepository) UpdateMatch(m Match) error {
 	db := storage.GetDB()
-	_, err := db.Exec("UPDATE matches SET home_goals = ?, away_goals = ?, played = ? WHERE id = ?",
-		nullableInt(m.HomeGoals), nullableInt(m.AwayGoals), m.Played, m.ID)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := updateMatchTx(tx, m); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r SQLiteMatchRepository) CreateMatch(m Match) error {
+// UpdateMatchAndRatings stores a match like UpdateMatch and, in the same
+// transaction, the season's replayed ratings, so a result is never stored
+// without the ratings that follow from it
+func (r SQLiteMatchRepository) UpdateMatchAndRatings(m Match, teams []Team, history []RatingPoint) error {
 	db := storage.GetDB()
-	_, err := db.Exec("INSERT INTO matches (id, home_team_id, away_team_id, week) VALUES (?, ?, ?, ?)", m.ID, m.HomeTeamID, m.AwayTeamID, m.Week)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := updateMatchTx(tx, m); err != nil {
+		return err
+	}
+	if err := saveRatingsTx(tx, m.SeasonID, teams, history); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func updateMatchTx(tx storage.DBTX, m Match) error {
+	rows, err := tx.Query("SELECT "+matchColumns+" FROM matches WHERE id = ?", m.ID)
+	if err != nil {
+		return err
+	}
+	existing, err := scanMatches(rows)
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return ErrNotFound
+	}
+	old := existing[0]
+	if err := checkTransition(m.ID, old.Status, m.Status); err != nil {
+		return err
+	}
+	rules, err := seasonScoringRules(old.SeasonID)
+	if err != nil {
+		return err
+	}
+	if err := applyResult(tx, rules, old, -1); err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE matches SET home_goals = ?, away_goals = ?, played = ?, status = ? WHERE id = ?",
+		nullableInt(m.HomeGoals), nullableInt(m.AwayGoals), m.Status.CountsInTable(), m.Status, m.ID)
+	if err != nil {
+		return err
+	}
+	if m.Events != nil {
+		if err := writeMatchEvents(tx, m.ID, m.Events); err != nil {
+			return err
+		}
+	}
+	updated := old
+	updated.HomeGoals, updated.AwayGoals, updated.Status = m.HomeGoals, m.AwayGoals, m.Status
+	return applyResult(tx, rules, updated, 1)
+}
+
+// CreateMatch inserts an unplayed fixture and returns its ID
+func (r SQLiteMatchRepository) CreateMatch(m Match) (int, error) {
+	return createMatchTx(storage.GetDB(), m)
+}
+
+func createMatchTx(tx storage.DBTX, m Match) (int, error) {
+	// A zero ID lets SQLite assign the next free one
+	var id interface{}
+	if m.ID != 0 {
+		id = m.ID
+	}
+	res, err := tx.Exec("INSERT INTO matches (id, season_id, home_team_id, away_team_id, week) VALUES (?, ?, ?, ?, ?)", id, m.SeasonID, m.HomeTeamID, m.AwayTeamID, m.Week)
+	if err != nil {
+		return 0, err
+	}
+	newID, err := res.LastInsertId()
+	return int(newID), err
+}
+
+// DeleteMatch removes a fixture and its events in one transaction,
+// returning ErrNotFound if it does not exist
+func (r SQLiteMatchRepository) DeleteMatch(id int) error {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM match_events WHERE match_id = ?", id); err != nil {
+		return err
+	}
+	res, err := tx.Exec("DELETE FROM matches WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+// deleteMatchesBySeasonTx removes every fixture of a season, played or
+// not, with its events
+func deleteMatchesBySeasonTx(tx storage.DBTX, seasonID int) error {
+	if _, err := tx.Exec("DELETE FROM match_events WHERE match_id IN (SELECT id FROM matches WHERE season_id = ?)", seasonID); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM matches WHERE season_id = ?", seasonID)
 	return err
 }
 
+// ReplaceSeasonMatches swaps a season's fixtures, events included, for the
+// given ones in one transaction, so a failure leaves the old schedule
+func (r SQLiteMatchRepository) ReplaceSeasonMatches(seasonID int, matches []Match) error {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := replaceSeasonMatchesTx(tx, seasonID, matches); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceSeasonMatchesTx(tx storage.DBTX, seasonID int, matches []Match) error {
+	if err := deleteMatchesBySeasonTx(tx, seasonID); err != nil {
+		return err
+	}
+	for _, m := range matches {
+		m.SeasonID = seasonID
+		if _, err := createMatchTx(tx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Helper to handle sql.NullInt64 for nullable fields
 func nullableInt(n sql.NullInt64) interface{} {
 	if n.Valid {
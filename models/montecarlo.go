@@ -0,0 +1,124 @@
+package models
+
+import (
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// MonteCarlo plays out the unplayed matches of a league many times. Runs
+// are spread over a pool of workers, and every run draws from its own
+// generator seeded from Seed and the run number, so the same seed gives
+// the same results whatever the number of workers.
+type MonteCarlo struct {
+	Simulator   MatchSimulator
+	Simulations int
+	Seed        int64
+	// Workers defaults to the number of CPUs
+	Workers int
+}
+
+// runSeed derives the seed of one run from the master seed
+func runSeed(seed int64, run int) int64 {
+	return int64(splitMix64(uint64(seed) ^ splitMix64(uint64(run))))
+}
+
+// SimulateOnce returns a copy of the league with every match that has no
+// counting result played in the given run, including postponed ones
+func (mc MonteCarlo) SimulateOnce(league League, run int) League {
+	mc.Simulator = BindSimulator(mc.Simulator, league)
+	return mc.simulate(league, rand.New(rand.NewSource(runSeed(mc.Seed, run))))
+}
+
+func (mc MonteCarlo) simulate(league League, rng *rand.Rand) League {
+	teams := weekTeams(league)
+	matches := make([]Match, len(league.Matches))
+	copy(matches, league.Matches)
+	// Play in week order so simulators that follow the league, such as
+	// form, see the results of earlier weeks
+	order := make([]int, len(matches))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return matches[order[i]].Week < matches[order[j]].Week })
+	var sim MatchSimulator
+	boundWeek := -1
+	for _, i := range order {
+		m := &matches[i]
+		if m.Status.CountsInTable() {
+			continue
+		}
+		if sim == nil || m.Week != boundWeek {
+			current := league
+			current.Matches = matches
+			sim, boundWeek = BindSimulator(mc.Simulator, current), m.Week
+		}
+		week := teams(m.Week)
+		m.SetResult(sim.SimulateMatchWithRand(week[m.HomeTeamID], week[m.AwayTeamID], rng))
+	}
+	league.Matches = matches
+	return league
+}
+
+// Run plays every simulation and collects the final tables in a forecast
+func (mc MonteCarlo) Run(league League) *Forecast {
+	// Bind once to the league as it stands, so simulators fitted to its
+	// results, such as Dixon-Coles, are fitted once and not on simulated
+	// results
+	mc.Simulator = BindSimulator(mc.Simulator, league)
+	workers := mc.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > mc.Simulations {
+		workers = mc.Simulations
+	}
+	runs := make(chan int)
+	partial := make([]*Forecast, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		partial[w] = NewForecast(league.Teams)
+		wg.Add(1)
+		go func(f *Forecast) {
+			defer wg.Done()
+			// One generator per worker, reseeded for every run it plays
+			rng := rand.New(rand.NewSource(0))
+			for run := range runs {
+				rng.Seed(runSeed(mc.Seed, run))
+				played := mc.simulate(league, rng)
+				f.Add(played.CalculateTable())
+			}
+		}(partial[w])
+	}
+	for run := 0; run < mc.Simulations; run++ {
+		runs <- run
+	}
+	close(runs)
+	wg.Wait()
+	forecast := NewForecast(league.Teams)
+	for _, f := range partial {
+		forecast.merge(f)
+	}
+	return forecast
+}
+
+// weekTeams returns a lookup of the teams as they stand in a week, without
+// the players the league's absences rule out then
+func weekTeams(league League) func(week int) map[int]Team {
+	byWeek := make(map[int]map[int]Team)
+	return func(week int) map[int]Team {
+		if len(league.Absences) == 0 {
+			week = 0
+		}
+		teams, ok := byWeek[week]
+		if !ok {
+			teams = make(map[int]Team)
+			for _, t := range AvailableTeams(league.Teams, league.Absences, week) {
+				teams[t.ID] = t
+			}
+			byWeek[week] = teams
+		}
+		return teams
+	}
+}
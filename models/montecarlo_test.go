@@ -0,0 +1,49 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMonteCarloSameSeedAnyWorkers(t *testing.T) {
+	teams := []Team{
+		{ID: 1, Name: "Lions", Strength: 90},
+		{ID: 2, Name: "Tigers", Strength: 80},
+		{ID: 3, Name: "Bears", Strength: 70},
+		{ID: 4, Name: "Wolves", Strength: 60},
+		{ID: 5, Name: "Eagles", Strength: 50},
+	}
+	matches := GenerateDoubleRoundRobin(teams)
+	for i := range matches {
+		matches[i].ID = i + 1
+	}
+	// Part of the season is already played
+	for i := range matches[:4] {
+		matches[i].SetResult(i%3, 1)
+	}
+	league := League{Teams: teams, Matches: matches}
+	sims := map[string]MatchSimulator{
+		"basic":   BasicMatchSimulator{},
+		"poisson": NewPoissonMatchSimulator(),
+		"form":    FormMatchSimulator{Simulator: NewPoissonMatchSimulator(), Window: DefaultFormWindow, Weight: DefaultFormWeight},
+	}
+	for name, sim := range sims {
+		t.Run(name, func(t *testing.T) {
+			var want []TeamForecast
+			for _, workers := range []int{1, 2, 3, 8} {
+				got := MonteCarlo{Simulator: sim, Simulations: 200, Seed: 42, Workers: workers}.Run(league).Summary(nil)
+				if want == nil {
+					want = got
+					continue
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%d workers gave %+v, 1 worker %+v", workers, got, want)
+				}
+			}
+			other := MonteCarlo{Simulator: sim, Simulations: 200, Seed: 43, Workers: 2}.Run(league).Summary(nil)
+			if reflect.DeepEqual(other, want) {
+				t.Error("a different seed gave the same forecast")
+			}
+		})
+	}
+}
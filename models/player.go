@@ -0,0 +1,409 @@
+package models
+
+import (
+	"Case_study/storage"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Position is where a player lines up
+type Position string
+
+const (
+	PositionGoalkeeper Position = "GK"
+	PositionDefender   Position = "DEF"
+	PositionMidfielder Position = "MID"
+	PositionForward    Position = "FWD"
+)
+
+// AllPositions lists the positions in lineup order
+var AllPositions = []Position{PositionGoalkeeper, PositionDefender, PositionMidfielder, PositionForward}
+
+// Valid reports whether p is a known position
+func (p Position) Valid() bool {
+	for _, known := range AllPositions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ElevenSize is the number of players in a starting lineup
+const ElevenSize = 11
+
+// formation is the shape used when a lineup is picked automatically
+var formation = map[Position]int{PositionGoalkeeper: 1, PositionDefender: 4, PositionMidfielder: 4, PositionForward: 2}
+
+// Player is a member of a team's squad. Ratings use the same 1-100 scale
+// as team strength.
+type Player struct {
+	ID          int
+	TeamID      int
+	Name        string
+	Position    Position
+	Attack      int
+	Defence     int
+	Goalkeeping int
+	// Starting marks the player as part of the chosen lineup
+	Starting bool
+}
+
+// Validate rejects players without a name, with an unknown position or
+// with a rating out of range
+func (p Player) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("player name is required")
+	}
+	if !p.Position.Valid() {
+		return fmt.Errorf("position must be one of GK, DEF, MID or FWD")
+	}
+	for _, rating := range []int{p.Attack, p.Defence, p.Goalkeeping} {
+		if rating < MinStrength || rating > MaxStrength {
+			return fmt.Errorf("ratings must be between %d and %d", MinStrength, MaxStrength)
+		}
+	}
+	return nil
+}
+
+// overall is the rating used to rank players for their position
+func (p Player) overall() int {
+	switch p.Position {
+	case PositionGoalkeeper:
+		return p.Goalkeeping
+	case PositionDefender:
+		return p.Defence
+	case PositionForward:
+		return p.Attack
+	}
+	return (p.Attack + p.Defence) / 2
+}
+
+// ValidateLineup checks that ids name eleven different players of the
+// squad with exactly one goalkeeper
+func ValidateLineup(squad []Player, ids []int) error {
+	if len(ids) != ElevenSize {
+		return fmt.Errorf("a lineup needs exactly %d players", ElevenSize)
+	}
+	byID := make(map[int]Player)
+	for _, p := range squad {
+		byID[p.ID] = p
+	}
+	seen := make(map[int]bool)
+	keepers := 0
+	for _, id := range ids {
+		p, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("player %d is not in the squad", id)
+		}
+		if seen[id] {
+			return fmt.Errorf("player %d is listed more than once", id)
+		}
+		seen[id] = true
+		if p.Position == PositionGoalkeeper {
+			keepers++
+		}
+	}
+	if keepers != 1 {
+		return fmt.Errorf("a lineup needs exactly one goalkeeper")
+	}
+	return nil
+}
+
+// StartingEleven returns the lineup a squad plays with: the chosen lineup
+// when it is complete, otherwise the best players in a 4-4-2, filling gaps
+// with the best remaining outfield players. Players from an incomplete
+// chosen lineup are picked first for their position. Squads with fewer
+// than eleven players return everyone.
+func StartingEleven(squad []Player) []Player {
+	var chosen []Player
+	ids := []int{}
+	for _, p := range squad {
+		if p.Starting {
+			chosen = append(chosen, p)
+			ids = append(ids, p.ID)
+		}
+	}
+	if ValidateLineup(squad, ids) == nil {
+		return chosen
+	}
+	ranked := append([]Player(nil), squad...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Starting != ranked[j].Starting {
+			return ranked[i].Starting
+		}
+		return ranked[i].overall() > ranked[j].overall()
+	})
+	picked := make(map[int]bool)
+	var eleven []Player
+	for _, pos := range AllPositions {
+		need := formation[pos]
+		for _, p := range ranked {
+			if need == 0 {
+				break
+			}
+			if p.Position == pos {
+				eleven = append(eleven, p)
+				picked[p.ID] = true
+				need--
+			}
+		}
+	}
+	for _, p := range ranked {
+		if len(eleven) == ElevenSize {
+			break
+		}
+		if !picked[p.ID] && p.Position != PositionGoalkeeper {
+			eleven = append(eleven, p)
+			picked[p.ID] = true
+		}
+	}
+	// A squad without a goalkeeper still fields eleven
+	for _, p := range ranked {
+		if len(eleven) == ElevenSize {
+			break
+		}
+		if !picked[p.ID] {
+			eleven = append(eleven, p)
+		}
+	}
+	return eleven
+}
+
+// Positional weights: forwards carry most of the attack and defenders most
+// of the defence
+var (
+	attackWeight  = map[Position]float64{PositionDefender: 1, PositionMidfielder: 2, PositionForward: 3}
+	defenceWeight = map[Position]float64{PositionDefender: 3, PositionMidfielder: 2, PositionForward: 1}
+)
+
+// keeperShare is the part of a lineup's defence that comes from the
+// goalkeeper
+const keeperShare = 0.3
+
+// LineupStrength returns the attack and defence of a lineup on the 1-100
+// scale. Attack is the position-weighted mean of the outfield players'
+// attack; defence mixes their weighted defence with the goalkeeper's
+// goalkeeping. ok is false for lineups short of eleven players.
+func LineupStrength(eleven []Player) (attack float64, defence float64, ok bool) {
+	if len(eleven) < ElevenSize {
+		return 0, 0, false
+	}
+	var attackSum, attackWeights, defenceSum, defenceWeights, keeping float64
+	for _, p := range eleven {
+		if p.Position == PositionGoalkeeper {
+			keeping = float64(p.Goalkeeping)
+			continue
+		}
+		attackSum += attackWeight[p.Position] * float64(p.Attack)
+		attackWeights += attackWeight[p.Position]
+		defenceSum += defenceWeight[p.Position] * float64(p.Defence)
+		defenceWeights += defenceWeight[p.Position]
+	}
+	if attackWeights == 0 {
+		return 0, 0, false
+	}
+	attack = attackSum / attackWeights
+	defence = (1-keeperShare)*defenceSum/defenceWeights + keeperShare*keeping
+	return attack, defence, true
+}
+
+// GenerateSquad makes up an 18-player squad whose ratings spread around
+// the team's strength, for teams that have no real players entered
+func GenerateSquad(team Team, rng *rand.Rand) []Player {
+	counts := []struct {
+		position Position
+		n        int
+	}{{PositionGoalkeeper, 2}, {PositionDefender, 6}, {PositionMidfielder, 6}, {PositionForward, 4}}
+	rating := func(offset int) int {
+		r := team.Strength + offset + rng.Intn(21) - 10
+		if r < MinStrength {
+			return MinStrength
+		}
+		if r > MaxStrength {
+			return MaxStrength
+		}
+		return r
+	}
+	var squad []Player
+	for _, c := range counts {
+		for i := 1; i <= c.n; i++ {
+			p := Player{
+				TeamID:      team.ID,
+				Name:        fmt.Sprintf("%s %s %d", team.Name, c.position, i),
+				Position:    c.position,
+				Attack:      rating(-30),
+				Defence:     rating(-30),
+				Goalkeeping: rating(-60),
+			}
+			switch c.position {
+			case PositionGoalkeeper:
+				p.Goalkeeping = rating(0)
+			case PositionDefender:
+				p.Defence = rating(0)
+				p.Attack = rating(-20)
+			case PositionMidfielder:
+				p.Attack = rating(-5)
+				p.Defence = rating(-5)
+			case PositionForward:
+				p.Attack = rating(0)
+			}
+			squad = append(squad, p)
+		}
+	}
+	return squad
+}
+
+// PlayerRepository defines DB operations for players
+type PlayerRepository interface {
+	GetPlayersByTeam(teamID int) ([]Player, error)
+	GetPlayersByLeague(leagueID int) ([]Player, error)
+	GetPlayerByID(id int) (Player, error)
+	CreatePlayer(player Player) (int, error)
+	UpdatePlayer(player Player) error
+	DeletePlayer(id int) error
+	SetLineup(teamID int, playerIDs []int) error
+}
+
+// SQLitePlayerRepository implements PlayerRepository using SQLite
+type SQLitePlayerRepository struct{}
+
+const playerColumns = "id, team_id, name, position, attack, defence, goalkeeping, starting"
+
+func scanPlayer(row interface{ Scan(...interface{}) error }) (Player, error) {
+	var p Player
+	err := row.Scan(&p.ID, &p.TeamID, &p.Name, &p.Position, &p.Attack, &p.Defence, &p.Goalkeeping, &p.Starting)
+	return p, err
+}
+
+func scanPlayers(rows *sql.Rows) ([]Player, error) {
+	defer rows.Close()
+	players := []Player{}
+	for rows.Next() {
+		p, err := scanPlayer(rows)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+// positionOrder sorts squads goalkeepers first, then by name
+const positionOrder = `ORDER BY CASE position WHEN 'GK' THEN 0 WHEN 'DEF' THEN 1 WHEN 'MID' THEN 2 ELSE 3 END, name, id`
+
+// GetPlayersByTeam returns a team's squad
+func (r SQLitePlayerRepository) GetPlayersByTeam(teamID int) ([]Player, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT "+playerColumns+" FROM players WHERE team_id = ? "+positionOrder, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return scanPlayers(rows)
+}
+
+// GetPlayersByLeague returns the squads of every team in a league
+func (r SQLitePlayerRepository) GetPlayersByLeague(leagueID int) ([]Player, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT "+playerColumns+" FROM players WHERE team_id IN (SELECT id FROM teams WHERE league_id = ?) "+positionOrder, leagueID)
+	if err != nil {
+		return nil, err
+	}
+	return scanPlayers(rows)
+}
+
+// GetPlayerByID returns ErrNotFound if the player does not exist
+func (r SQLitePlayerRepository) GetPlayerByID(id int) (Player, error) {
+	db := storage.GetDB()
+	p, err := scanPlayer(db.QueryRow("SELECT "+playerColumns+" FROM players WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return p, ErrNotFound
+	}
+	return p, err
+}
+
+// CreatePlayer adds a player to a squad and returns its ID
+func (r SQLitePlayerRepository) CreatePlayer(player Player) (int, error) {
+	db := storage.GetDB()
+	res, err := db.Exec("INSERT INTO players (team_id, name, position, attack, defence, goalkeeping, starting) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		player.TeamID, player.Name, player.Position, player.Attack, player.Defence, player.Goalkeeping, player.Starting)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// UpdatePlayer returns ErrNotFound if the player does not exist. The
+// player's team and lineup place are not changed.
+func (r SQLitePlayerRepository) UpdatePlayer(player Player) error {
+	db := storage.GetDB()
+	res, err := db.Exec("UPDATE players SET name = ?, position = ?, attack = ?, defence = ?, goalkeeping = ? WHERE id = ?",
+		player.Name, player.Position, player.Attack, player.Defence, player.Goalkeeping, player.ID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeletePlayer removes a player from a squad together with their manual
+// absences. Events the player was part of keep the name but lose the link.
+func (r SQLitePlayerRepository) DeletePlayer(id int) error {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("UPDATE match_events SET player_id = NULL WHERE player_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE match_events SET related_player_id = NULL WHERE related_player_id = ?", id); err != nil {
+		return err
+	}
+	if err := deletePlayerAbsences(tx, id); err != nil {
+		return err
+	}
+	res, err := tx.Exec("DELETE FROM players WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
+
+// SetLineup makes the given players the team's starting lineup; an empty
+// list clears it so the lineup is picked automatically
+func (r SQLitePlayerRepository) SetLineup(teamID int, playerIDs []int) error {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("UPDATE players SET starting = 0 WHERE team_id = ?", teamID); err != nil {
+		return err
+	}
+	for _, id := range playerIDs {
+		if _, err := tx.Exec("UPDATE players SET starting = 1 WHERE id = ? AND team_id = ?", id, teamID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
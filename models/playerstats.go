@@ -0,0 +1,208 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PlayerStats is one player's record over a run of matches
+type PlayerStats struct {
+	// PlayerID is zero for players only known by the name on an event
+	PlayerID    int
+	TeamID      int
+	Name        string
+	Appearances int
+	Minutes     int
+	Goals       int
+	Assists     int
+	CleanSheets int
+	YellowCards int
+	RedCards    int
+}
+
+// cleanSheetMinutes is how long a goalkeeper must play for a clean sheet
+const cleanSheetMinutes = 60
+
+// playerKey identifies a player on the timeline; name is only used for
+// events that are not linked to a squad member
+type playerKey struct {
+	teamID   int
+	playerID int
+	name     string
+}
+
+func eventPlayer(teamID int, id int, name string) (playerKey, bool) {
+	if id != 0 {
+		return playerKey{teamID: teamID, playerID: id}, true
+	}
+	return playerKey{teamID: teamID, name: name}, name != ""
+}
+
+// ComputePlayerStats replays the stored events of the completed matches up
+// to and including throughWeek (every week when it is zero). Lineup events
+// start a player's minutes, substitutions, injuries and red cards end them
+// and everyone else plays to full time. A goalkeeper keeps a clean sheet by
+// playing at least 60 minutes of a match the team did not concede in.
+func ComputePlayerStats(teams []Team, matches []Match, events []MatchEvent, throughWeek int) []PlayerStats {
+	squad := make(map[int]Player)
+	for _, t := range teams {
+		for _, p := range t.Players {
+			squad[p.ID] = p
+		}
+	}
+	byMatch := make(map[int][]MatchEvent)
+	for _, e := range events {
+		byMatch[e.MatchID] = append(byMatch[e.MatchID], e)
+	}
+	stats := make(map[playerKey]*PlayerStats)
+	get := func(k playerKey, name string) *PlayerStats {
+		s, ok := stats[k]
+		if !ok {
+			s = &PlayerStats{PlayerID: k.playerID, TeamID: k.teamID, Name: name}
+			if p, known := squad[k.playerID]; known {
+				s.Name = p.Name
+			}
+			stats[k] = s
+		}
+		return s
+	}
+	for _, m := range matches {
+		if m.Status != StatusCompleted || (throughWeek > 0 && m.Week > throughWeek) {
+			continue
+		}
+		timeline := byMatch[m.ID]
+		sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].Minute < timeline[j].Minute })
+		onSince := make(map[playerKey]int)
+		played := make(map[playerKey]int)
+		leave := func(k playerKey, minute int) {
+			if start, ok := onSince[k]; ok {
+				played[k] += minute - start
+				delete(onSince, k)
+			}
+		}
+		for _, e := range timeline {
+			k, ok := eventPlayer(e.TeamID, e.PlayerID, e.Player)
+			if !ok {
+				continue
+			}
+			s := get(k, e.Player)
+			switch e.Type {
+			case EventLineup:
+				s.Appearances++
+				onSince[k] = 0
+			case EventGoal:
+				s.Goals++
+				if a, ok := eventPlayer(e.TeamID, e.RelatedPlayerID, e.RelatedPlayer); ok {
+					get(a, e.RelatedPlayer).Assists++
+				}
+			case EventYellowCard:
+				s.YellowCards++
+			case EventRedCard:
+				s.RedCards++
+				leave(k, e.Minute)
+			case EventSubstitution, EventInjury:
+				leave(k, e.Minute)
+				if on, ok := eventPlayer(e.TeamID, e.RelatedPlayerID, e.RelatedPlayer); ok {
+					get(on, e.RelatedPlayer).Appearances++
+					onSince[on] = e.Minute
+				}
+			}
+		}
+		for k := range onSince {
+			leave(k, MatchMinutes)
+		}
+		for k, minutes := range played {
+			if minutes < 0 {
+				minutes = 0
+			}
+			s := get(k, "")
+			s.Minutes += minutes
+			conceded := m.AwayGoals.Int64
+			if k.teamID == m.AwayTeamID {
+				conceded = m.HomeGoals.Int64
+			}
+			if squad[k.playerID].Position == PositionGoalkeeper && minutes >= cleanSheetMinutes && conceded == 0 {
+				s.CleanSheets++
+			}
+		}
+	}
+	result := []PlayerStats{}
+	for _, s := range stats {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TeamID != result[j].TeamID {
+			return result[i].TeamID < result[j].TeamID
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// LeaderboardCategory names a statistic players are ranked by
+type LeaderboardCategory string
+
+const (
+	LeaderboardScorers     LeaderboardCategory = "scorers"
+	LeaderboardAssists     LeaderboardCategory = "assists"
+	LeaderboardCleanSheets LeaderboardCategory = "clean_sheets"
+	LeaderboardCards       LeaderboardCategory = "cards"
+	LeaderboardMinutes     LeaderboardCategory = "minutes"
+)
+
+// LeaderboardCategories lists every category in display order
+var LeaderboardCategories = []LeaderboardCategory{LeaderboardScorers, LeaderboardAssists, LeaderboardCleanSheets, LeaderboardCards, LeaderboardMinutes}
+
+// ParseLeaderboardCategory rejects unknown category names
+func ParseLeaderboardCategory(s string) (LeaderboardCategory, error) {
+	for _, c := range LeaderboardCategories {
+		if LeaderboardCategory(s) == c {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown leaderboard %q", s)
+}
+
+// Value returns the statistic a player is ranked by. Cards count a red as
+// two, the way most fair play tables do.
+func (c LeaderboardCategory) Value(s PlayerStats) int {
+	switch c {
+	case LeaderboardScorers:
+		return s.Goals
+	case LeaderboardAssists:
+		return s.Assists
+	case LeaderboardCleanSheets:
+		return s.CleanSheets
+	case LeaderboardCards:
+		return s.YellowCards + 2*s.RedCards
+	case LeaderboardMinutes:
+		return s.Minutes
+	}
+	return 0
+}
+
+// Leaderboard ranks the players with a non-zero value in the category,
+// breaking ties by fewer minutes played and then by name. limit caps the
+// length when positive.
+func Leaderboard(stats []PlayerStats, category LeaderboardCategory, limit int) []PlayerStats {
+	board := []PlayerStats{}
+	for _, s := range stats {
+		if category.Value(s) > 0 {
+			board = append(board, s)
+		}
+	}
+	sort.SliceStable(board, func(i, j int) bool {
+		vi, vj := category.Value(board[i]), category.Value(board[j])
+		if vi != vj {
+			return vi > vj
+		}
+		if category != LeaderboardMinutes && board[i].Minutes != board[j].Minutes {
+			return board[i].Minutes < board[j].Minutes
+		}
+		return board[i].Name < board[j].Name
+	})
+	if limit > 0 && len(board) > limit {
+		board = board[:limit]
+	}
+	return board
+}
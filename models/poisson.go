@@ -0,0 +1,74 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+)
+
+// PoissonMatchSimulator draws each side's goals from a Poisson distribution
+// whose mean depends on the strength ratio of the two teams
+type PoissonMatchSimulator struct {
+	// AverageGoals is the expected goals for a side facing an equal opponent
+	// on neutral ground
+	AverageGoals float64
+	// HomeAdvantage multiplies the home side's expected goals and divides
+	// the away side's
+	HomeAdvantage float64
+}
+
+// MaxExpectedGoals caps a side's expected goals, however lopsided the
+// match, so scores stay football scores
+const MaxExpectedGoals = 6.0
+
+// capExpectedGoals bounds expected goals to MaxExpectedGoals
+func capExpectedGoals(xg float64) float64 {
+	return math.Min(xg, MaxExpectedGoals)
+}
+
+// NewPoissonMatchSimulator returns a simulator with typical football defaults
+func NewPoissonMatchSimulator() PoissonMatchSimulator {
+	return PoissonMatchSimulator{AverageGoals: 1.35, HomeAdvantage: 1.2}
+}
+
+// ExpectedGoals returns the Poisson means for the home and away side.
+// A team's current strength acts as its attack rating and as its defence
+// rating, so the stronger side both scores more and concedes less. Each
+// mean is capped at MaxExpectedGoals.
+func (p PoissonMatchSimulator) ExpectedGoals(home Team, away Team) (float64, float64) {
+	homeStrength := math.Max(home.CurrentStrength(), 1)
+	awayStrength := math.Max(away.CurrentStrength(), 1)
+	advantage := p.HomeAdvantage
+	if advantage <= 0 {
+		advantage = 1
+	}
+	homeXG := p.AverageGoals * advantage * homeStrength / awayStrength
+	awayXG := p.AverageGoals / advantage * awayStrength / homeStrength
+	return capExpectedGoals(homeXG), capExpectedGoals(awayXG)
+}
+
+// SimulateMatch returns simulated goals for home and away teams
+func (p PoissonMatchSimulator) SimulateMatch(home Team, away Team) (int, int) {
+	return p.SimulateMatchWithRand(home, away, newRand())
+}
+
+// SimulateMatchWithRand is SimulateMatch drawing from the given generator
+func (p PoissonMatchSimulator) SimulateMatchWithRand(home Team, away Team, rng *rand.Rand) (int, int) {
+	homeXG, awayXG := p.ExpectedGoals(home, away)
+	return samplePoisson(homeXG, rng), samplePoisson(awayXG, rng)
+}
+
+// samplePoisson draws from a Poisson distribution using Knuth's method,
+// which is fast enough for the small means seen in football
+func samplePoisson(lambda float64, rng *rand.Rand) int {
+	if lambda <= 0 {
+		return 0
+	}
+	limit := math.Exp(-lambda)
+	k := 0
+	p := rng.Float64()
+	for p > limit {
+		k++
+		p *= rng.Float64()
+	}
+	return k
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPoissonExpectedGoalsExtremeStrengths(t *testing.T) {
+	sim := NewPoissonMatchSimulator()
+	strong, weak := Team{ID: 1, Strength: 100}, Team{ID: 2, Strength: 1}
+	for _, pair := range [][2]Team{{strong, weak}, {weak, strong}} {
+		homeXG, awayXG := sim.ExpectedGoals(pair[0], pair[1])
+		if homeXG > MaxExpectedGoals || awayXG > MaxExpectedGoals {
+			t.Errorf("strengths %d v %d: xG %.2f-%.2f above %g", pair[0].Strength, pair[1].Strength, homeXG, awayXG, MaxExpectedGoals)
+		}
+		if homeXG <= 0 || awayXG <= 0 {
+			t.Errorf("strengths %d v %d: xG %.2f-%.2f not positive", pair[0].Strength, pair[1].Strength, homeXG, awayXG)
+		}
+		matrix := ScoreMatrix(homeXG, awayXG, 0, predictionMaxGoals)
+		// The scorelines covered should hold nearly all the probability
+		// before normalisation, so the cap keeps them meaningful
+		covered := 0.0
+		homeP, awayP := poissonPMF(homeXG, predictionMaxGoals), poissonPMF(awayXG, predictionMaxGoals)
+		for x := range matrix {
+			for y := range matrix[x] {
+				covered += homeP[x] * awayP[y]
+			}
+		}
+		if covered < 0.95 {
+			t.Errorf("strengths %d v %d: scorelines up to %d cover only %.3f", pair[0].Strength, pair[1].Strength, predictionMaxGoals, covered)
+		}
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		if h, a := sim.SimulateMatchWithRand(strong, weak, rng); h > 25 || a > 25 {
+			t.Fatalf("simulated %d-%d", h, a)
+		}
+	}
+}
+
+func TestPoissonExpectedGoalsTypicalStrengths(t *testing.T) {
+	homeXG, awayXG := NewPoissonMatchSimulator().ExpectedGoals(Team{Strength: 90}, Team{Strength: 60})
+	if homeXG < 2.42 || homeXG > 2.44 || awayXG < 0.74 || awayXG > 0.76 {
+		t.Errorf("xG %.3f-%.3f, want the uncapped 2.43-0.75", homeXG, awayXG)
+	}
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// GoalModel is a simulator that draws goals from known Poisson means, so a
+// match's outcome probabilities can be worked out without sampling
+type GoalModel interface {
+	ExpectedGoals(home Team, away Team) (homeXG float64, awayXG float64)
+}
+
+// lowScoreCorrection is a goal model that corrects the odds of low scores
+// the way Dixon-Coles does
+type lowScoreCorrection interface {
+	Rho() float64
+}
+
+// predictionMaxGoals bounds the scorelines an analytic prediction covers;
+// the probability beyond it is negligible for football scores
+const predictionMaxGoals = 10
+
+// Prediction is the probability of every outcome of a match
+type Prediction struct {
+	// Scores holds the probability of each scoreline, indexed [home][away]
+	Scores [][]float64
+	HomeXG float64
+	AwayXG float64
+	// Analytic is false when the scores were sampled, Samples times
+	Analytic bool
+	Samples  int
+}
+
+// ScorelineProbability is the probability of one final score
+type ScorelineProbability struct {
+	HomeGoals   int
+	AwayGoals   int
+	Probability float64
+}
+
+// PredictMatch works out the probability of every score between two teams.
+// Simulators that expose their goal model are computed exactly; any other
+// is sampled samples times from rng. Form is applied before either.
+func PredictMatch(sim MatchSimulator, home Team, away Team, samples int, rng *rand.Rand) Prediction {
+	if f, ok := sim.(FormMatchSimulator); ok {
+		return PredictMatch(f.Simulator, f.withForm(home), f.withForm(away), samples, rng)
+	}
+	if model, ok := sim.(GoalModel); ok {
+		homeXG, awayXG := model.ExpectedGoals(home, away)
+		rho := 0.0
+		if c, ok := sim.(lowScoreCorrection); ok {
+			rho = c.Rho()
+		}
+		return Prediction{Scores: ScoreMatrix(homeXG, awayXG, rho, predictionMaxGoals), HomeXG: homeXG, AwayXG: awayXG, Analytic: true}
+	}
+	p := Prediction{Samples: samples}
+	counts := map[[2]int]int{}
+	maxGoals := 0
+	for i := 0; i < samples; i++ {
+		h, a := sim.SimulateMatchWithRand(home, away, rng)
+		counts[[2]int{h, a}]++
+		p.HomeXG += float64(h)
+		p.AwayXG += float64(a)
+		if h > maxGoals {
+			maxGoals = h
+		}
+		if a > maxGoals {
+			maxGoals = a
+		}
+	}
+	p.Scores = make([][]float64, maxGoals+1)
+	for x := range p.Scores {
+		p.Scores[x] = make([]float64, maxGoals+1)
+	}
+	if samples > 0 {
+		for score, n := range counts {
+			p.Scores[score[0]][score[1]] = float64(n) / float64(samples)
+		}
+		p.HomeXG /= float64(samples)
+		p.AwayXG /= float64(samples)
+	}
+	return p
+}
+
+// Outcome returns the probabilities of a home win, a draw and an away win
+func (p Prediction) Outcome() (home float64, draw float64, away float64) {
+	for x := range p.Scores {
+		for y, prob := range p.Scores[x] {
+			switch {
+			case x > y:
+				home += prob
+			case x < y:
+				away += prob
+			default:
+				draw += prob
+			}
+		}
+	}
+	return home, draw, away
+}
+
+// TopScorelines returns the limit most likely scores, most likely first
+func (p Prediction) TopScorelines(limit int) []ScorelineProbability {
+	scores := []ScorelineProbability{}
+	for x := range p.Scores {
+		for y, prob := range p.Scores[x] {
+			if prob > 0 {
+				scores = append(scores, ScorelineProbability{HomeGoals: x, AwayGoals: y, Probability: prob})
+			}
+		}
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Probability > scores[j].Probability })
+	if limit > 0 && len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return scores
+}
+
+// GoalDistributions returns the probability of each number of home goals,
+// away goals and total goals
+func (p Prediction) GoalDistributions() (home []float64, away []float64, total []float64) {
+	n := len(p.Scores)
+	home, away, total = make([]float64, n), make([]float64, n), make([]float64, 2*n-1)
+	for x := range p.Scores {
+		for y, prob := range p.Scores[x] {
+			home[x] += prob
+			away[y] += prob
+			total[x+y] += prob
+		}
+	}
+	return home, away, total
+}
+
+// Over returns the probability that more than line goals are scored
+// according to a goal distribution
+func Over(distribution []float64, line float64) float64 {
+	over := 0.0
+	for goals, prob := range distribution {
+		if float64(goals) > line {
+			over += prob
+		}
+	}
+	return over
+}
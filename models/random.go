@@ -0,0 +1,10 @@
+package models
+
+// splitMix64 scrambles a 64-bit value; it is used to derive independent,
+// reproducible values from a seed without allocating a rand.Source
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
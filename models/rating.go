@@ -0,0 +1,187 @@
+package models
+
+import (
+	"Case_study/storage"
+	"math"
+	"sort"
+)
+
+// RatingPoint is a team's rating after the given week; week 0 holds the
+// starting rating
+type RatingPoint struct {
+	TeamID int
+	Week   int
+	Rating float64
+}
+
+// InitialRating maps a team's seed strength onto the Elo scale
+func InitialRating(strength int) float64 {
+	return 1000 + 10*float64(strength)
+}
+
+// StrengthFromRating converts an Elo rating back to the strength scale
+// used by the simulators
+func StrengthFromRating(rating float64) float64 {
+	return math.Max((rating-1000)/10, 1)
+}
+
+// EloRater updates team ratings from match results
+type EloRater struct {
+	// K is the maximum rating change for a one-goal result
+	K float64
+	// HomeAdvantage is added to the home side's rating when computing the
+	// expected result
+	HomeAdvantage float64
+}
+
+// NewEloRater returns a rater with the usual club football settings
+func NewEloRater() EloRater {
+	return EloRater{K: 20, HomeAdvantage: 60}
+}
+
+// Expected returns the home side's expected score between 0 and 1
+func (e EloRater) Expected(homeRating float64, awayRating float64) float64 {
+	return 1 / (1 + math.Pow(10, (awayRating-homeRating-e.HomeAdvantage)/400))
+}
+
+// Update returns both ratings after a single result
+func (e EloRater) Update(homeRating float64, awayRating float64, homeGoals int, awayGoals int) (float64, float64) {
+	actual := 0.5
+	if homeGoals > awayGoals {
+		actual = 1
+	} else if homeGoals < awayGoals {
+		actual = 0
+	}
+	// Wider margins move ratings further, as in the World Football Elo
+	margin := homeGoals - awayGoals
+	if margin < 0 {
+		margin = -margin
+	}
+	multiplier := 1.0
+	if margin == 2 {
+		multiplier = 1.5
+	} else if margin > 2 {
+		multiplier = float64(11+margin) / 8
+	}
+	delta := e.K * multiplier * (actual - e.Expected(homeRating, awayRating))
+	return homeRating + delta, awayRating - delta
+}
+
+// Rate replays every played match of a season in week order and returns the
+// current ratings together with a snapshot of all teams after every week that
+// had results. Teams start from their entry in start, which carries ratings
+// over from the previous season, or else from their seed strength.
+func (e EloRater) Rate(teams []Team, matches []Match, start map[int]float64) (map[int]float64, []RatingPoint) {
+	ratings := make(map[int]float64)
+	var history []RatingPoint
+	for _, t := range teams {
+		if rating, ok := start[t.ID]; ok {
+			ratings[t.ID] = rating
+		} else {
+			ratings[t.ID] = InitialRating(t.Strength)
+		}
+		history = append(history, RatingPoint{TeamID: t.ID, Week: 0, Rating: ratings[t.ID]})
+	}
+	// Awarded results were not played on the pitch, so they do not move
+	// ratings
+	var played []Match
+	for _, m := range matches {
+		if m.Status == StatusCompleted && m.HomeGoals.Valid && m.AwayGoals.Valid {
+			played = append(played, m)
+		}
+	}
+	sort.SliceStable(played, func(i, j int) bool {
+		if played[i].Week != played[j].Week {
+			return played[i].Week < played[j].Week
+		}
+		return played[i].ID < played[j].ID
+	})
+	for i, m := range played {
+		home, okHome := ratings[m.HomeTeamID]
+		away, okAway := ratings[m.AwayTeamID]
+		if okHome && okAway {
+			ratings[m.HomeTeamID], ratings[m.AwayTeamID] = e.Update(home, away, int(m.HomeGoals.Int64), int(m.AwayGoals.Int64))
+		}
+		if i == len(played)-1 || played[i+1].Week != m.Week {
+			for _, t := range teams {
+				history = append(history, RatingPoint{TeamID: t.ID, Week: m.Week, Rating: ratings[t.ID]})
+			}
+		}
+	}
+	return ratings, history
+}
+
+// SQLiteRatingRepository stores current ratings and their weekly history
+type SQLiteRatingRepository struct{}
+
+// saveRatingsTx replaces a season's rating history and each team's current
+// rating
+func saveRatingsTx(tx storage.DBTX, seasonID int, teams []Team, history []RatingPoint) error {
+	if err := writeRatingHistory(tx, seasonID, history); err != nil {
+		return err
+	}
+	for _, t := range teams {
+		if _, err := tx.Exec("UPDATE teams SET rating = ? WHERE id = ?", t.Rating, t.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRatingHistory replaces a season's stored rating history
+func writeRatingHistory(tx storage.DBTX, seasonID int, history []RatingPoint) error {
+	if _, err := tx.Exec("DELETE FROM team_ratings WHERE season_id = ?", seasonID); err != nil {
+		return err
+	}
+	for _, p := range history {
+		if _, err := tx.Exec("INSERT INTO team_ratings (season_id, team_id, week, rating) VALUES (?, ?, ?, ?)", seasonID, p.TeamID, p.Week, p.Rating); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullableRating stores an unrated team as NULL
+func nullableRating(rating float64) interface{} {
+	if rating == 0 {
+		return nil
+	}
+	return rating
+}
+
+// GetRatingHistory returns a season's ratings ordered by team and week
+func (r SQLiteRatingRepository) GetRatingHistory(seasonID int) ([]RatingPoint, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT team_id, week, rating FROM team_ratings WHERE season_id = ? ORDER BY team_id, week", seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var history []RatingPoint
+	for rows.Next() {
+		var p RatingPoint
+		if err := rows.Scan(&p.TeamID, &p.Week, &p.Rating); err != nil {
+			return nil, err
+		}
+		history = append(history, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetStartRatings returns the week 0 ratings a season started from
+func (r SQLiteRatingRepository) GetStartRatings(seasonID int) (map[int]float64, error) {
+	history, err := r.GetRatingHistory(seasonID)
+	if err != nil {
+		return nil, err
+	}
+	start := make(map[int]float64)
+	for _, p := range history {
+		if p.Week == 0 {
+			start[p.TeamID] = p.Rating
+		}
+	}
+	return start, nil
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SimulatorParam describes a numeric setting of a registered simulator.
+// Names use underscores, the way they are passed in query strings.
+type SimulatorParam struct {
+	Name        string
+	Description string
+	Default     float64
+	// Min and Max bound the value, both inclusive
+	Min     float64
+	Max     float64
+	Integer bool
+}
+
+// Check rejects values out of range, or fractional ones for an integer
+func (p SimulatorParam) Check(v float64) error {
+	if math.IsNaN(v) || v < p.Min || v > p.Max {
+		return fmt.Errorf("%s must be between %g and %g", p.Name, p.Min, p.Max)
+	}
+	if p.Integer && v != math.Trunc(v) {
+		return fmt.Errorf("%s must be a whole number", p.Name)
+	}
+	return nil
+}
+
+// SimulatorParams holds parameter values by name
+type SimulatorParams map[string]float64
+
+// SimulatorSpec is a simulator in a registry: its parameters with their
+// defaults and a constructor that takes a value for every one of them
+type SimulatorSpec struct {
+	Name        string
+	Description string
+	Params      []SimulatorParam
+	New         func(p SimulatorParams) MatchSimulator
+}
+
+// FormParams are the parameters every registered simulator takes for the
+// form factor, which wraps it in a FormMatchSimulator when the weight is
+// positive
+var FormParams = []SimulatorParam{
+	{Name: "form_weight", Description: "share of strength recent form can add or take away; 0 disables the form factor", Default: 0, Min: 0, Max: 0.9},
+	{Name: "form_window", Description: "number of recent matches that make up form", Default: DefaultFormWindow, Min: 1, Max: 100, Integer: true},
+}
+
+// AllParams returns the simulator's own parameters followed by the form
+// parameters
+func (s SimulatorSpec) AllParams() []SimulatorParam {
+	return append(append([]SimulatorParam(nil), s.Params...), FormParams...)
+}
+
+// Param looks up one of the simulator's parameters, form ones included
+func (s SimulatorSpec) Param(name string) (SimulatorParam, bool) {
+	for _, p := range s.AllParams() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return SimulatorParam{}, false
+}
+
+// SimulatorConfig selects a registered simulator and the parameters that
+// differ from its defaults
+type SimulatorConfig struct {
+	Model  string
+	Params SimulatorParams
+}
+
+// SimulatorRegistry holds the simulators that can be selected by name
+type SimulatorRegistry struct {
+	specs map[string]SimulatorSpec
+	names []string
+}
+
+// NewSimulatorRegistry returns an empty registry
+func NewSimulatorRegistry() *SimulatorRegistry {
+	return &SimulatorRegistry{specs: make(map[string]SimulatorSpec)}
+}
+
+// Register adds a simulator. It panics if the name is taken, like
+// registering an HTTP handler twice.
+func (r *SimulatorRegistry) Register(spec SimulatorSpec) {
+	if _, ok := r.specs[spec.Name]; ok {
+		panic("models: simulator " + spec.Name + " registered twice")
+	}
+	r.specs[spec.Name] = spec
+	r.names = append(r.names, spec.Name)
+}
+
+// Specs returns the registered simulators in the order they were added
+func (r *SimulatorRegistry) Specs() []SimulatorSpec {
+	specs := make([]SimulatorSpec, 0, len(r.names))
+	for _, name := range r.names {
+		specs = append(specs, r.specs[name])
+	}
+	return specs
+}
+
+// Lookup returns a registered simulator or an error naming the known ones
+func (r *SimulatorRegistry) Lookup(name string) (SimulatorSpec, error) {
+	spec, ok := r.specs[name]
+	if !ok {
+		return spec, fmt.Errorf("unknown simulator %q, expected one of %v", name, r.names)
+	}
+	return spec, nil
+}
+
+// ParamNames returns the name of every parameter any registered simulator
+// takes, sorted
+func (r *SimulatorRegistry) ParamNames() []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, spec := range r.Specs() {
+		for _, p := range spec.AllParams() {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				names = append(names, p.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve fills in the defaults of a configuration and checks every value,
+// rejecting parameters the simulator does not take
+func (r *SimulatorRegistry) Resolve(c SimulatorConfig) (SimulatorSpec, SimulatorParams, error) {
+	spec, err := r.Lookup(c.Model)
+	if err != nil {
+		return spec, nil, err
+	}
+	values := make(SimulatorParams)
+	for _, p := range spec.AllParams() {
+		values[p.Name] = p.Default
+	}
+	for name, v := range c.Params {
+		p, ok := spec.Param(name)
+		if !ok {
+			return spec, nil, fmt.Errorf("simulator %s does not take %s", spec.Name, name)
+		}
+		if err := p.Check(v); err != nil {
+			return spec, nil, err
+		}
+		values[name] = v
+	}
+	return spec, values, nil
+}
+
+// Build resolves a configuration and returns its simulator, wrapped for
+// form when form_weight is positive, with the values it was built from
+func (r *SimulatorRegistry) Build(c SimulatorConfig) (MatchSimulator, SimulatorParams, error) {
+	spec, values, err := r.Resolve(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	sim := spec.New(values)
+	if values["form_weight"] > 0 {
+		sim = FormMatchSimulator{Simulator: sim, Window: int(values["form_window"]), Weight: values["form_weight"]}
+	}
+	return sim, values, nil
+}
+
+// poissonParams are the settings of the Poisson model and the models built
+// on it
+func poissonParams() []SimulatorParam {
+	defaults := NewPoissonMatchSimulator()
+	return []SimulatorParam{
+		{Name: "home_advantage", Description: "multiplies the home side's expected goals and divides the away side's", Default: defaults.HomeAdvantage, Min: 0.1, Max: 5},
+		{Name: "average_goals", Description: "expected goals per side between equal teams on neutral ground", Default: defaults.AverageGoals, Min: 0.1, Max: 10},
+	}
+}
+
+func poissonFromParams(p SimulatorParams) PoissonMatchSimulator {
+	return PoissonMatchSimulator{HomeAdvantage: p["home_advantage"], AverageGoals: p["average_goals"]}
+}
+
+// DixonColesOptionsFromParams returns the fit options among the values of
+// the dixon-coles simulator
+func DixonColesOptionsFromParams(p SimulatorParams) DixonColesOptions {
+	return DixonColesOptions{HalfLife: p["half_life"], Shrinkage: p["shrinkage"], AverageGoals: p["average_goals"], HomeAdvantage: p["home_advantage"]}
+}
+
+// DefaultSimulators returns a registry of the simulators in this package
+func DefaultSimulators() *SimulatorRegistry {
+	r := NewSimulatorRegistry()
+	r.Register(SimulatorSpec{
+		Name:        "basic",
+		Description: "strength-weighted scores of roughly 0-3 goals per side",
+		Params:      []SimulatorParam{},
+		New:         func(SimulatorParams) MatchSimulator { return BasicMatchSimulator{} },
+	})
+	r.Register(SimulatorSpec{
+		Name:        "poisson",
+		Description: "Poisson goals with means from the strength ratio of the two teams",
+		Params:      poissonParams(),
+		New:         func(p SimulatorParams) MatchSimulator { return poissonFromParams(p) },
+	})
+	r.Register(SimulatorSpec{
+		Name:        "squad",
+		Description: "the Poisson model with attack and defence taken from each side's starting eleven",
+		Params:      poissonParams(),
+		New: func(p SimulatorParams) MatchSimulator {
+			return SquadMatchSimulator{PoissonMatchSimulator: poissonFromParams(p)}
+		},
+	})
+	dixonColes := NewDixonColesMatchSimulator()
+	r.Register(SimulatorSpec{
+		Name:        "dixon-coles",
+		Description: "Dixon-Coles model fitted to the league's results; poisson until a match has been played",
+		Params: append(poissonParams(),
+			SimulatorParam{Name: "half_life", Description: "weeks after which a result counts half in the fit; 0 weights all results the same", Default: dixonColes.Options.HalfLife, Min: 0, Max: 1000},
+			SimulatorParam{Name: "shrinkage", Description: "how strongly the fit pulls ratings towards the teams' strength", Default: dixonColes.Options.Shrinkage, Min: 0, Max: 100},
+		),
+		New: func(p SimulatorParams) MatchSimulator {
+			return DixonColesMatchSimulator{PoissonMatchSimulator: poissonFromParams(p), Options: DixonColesOptionsFromParams(p)}
+		},
+	})
+	return r
+}
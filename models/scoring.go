@@ -0,0 +1,166 @@
+package models
+
+import (
+	"Case_study/storage"
+	"database/sql"
+	"errors"
+)
+
+// ScoringRules decides how many points a result is worth in a league
+type ScoringRules struct {
+	WinPoints  int
+	DrawPoints int
+	LossPoints int
+	// A side scoring at least GoalsBonusThreshold goals earns
+	// GoalsBonusPoints extra; a zero threshold disables the bonus
+	GoalsBonusThreshold int
+	GoalsBonusPoints    int
+	// A side losing by LosingBonusMargin goals or fewer earns
+	// LosingBonusPoints extra; a zero margin disables the bonus
+	LosingBonusMargin int
+	LosingBonusPoints int
+}
+
+// DefaultScoringRules is the modern three points for a win
+var DefaultScoringRules = ScoringRules{WinPoints: 3, DrawPoints: 1, LossPoints: 0}
+
+// Validate rejects rules that cannot produce a sensible table
+func (s ScoringRules) Validate() error {
+	if s.WinPoints < s.DrawPoints || s.DrawPoints < s.LossPoints {
+		return errors.New("points must satisfy win >= draw >= loss")
+	}
+	if s.GoalsBonusThreshold < 0 || s.LosingBonusMargin < 0 {
+		return errors.New("bonus thresholds cannot be negative")
+	}
+	return nil
+}
+
+// ResultPoints returns the points for winning, drawing or losing only
+func (s ScoringRules) ResultPoints(goalsFor int, goalsAgainst int) int {
+	if goalsFor > goalsAgainst {
+		return s.WinPoints
+	}
+	if goalsFor < goalsAgainst {
+		return s.LossPoints
+	}
+	return s.DrawPoints
+}
+
+// BonusPoints returns the extra points earned on top of the result
+func (s ScoringRules) BonusPoints(goalsFor int, goalsAgainst int) int {
+	bonus := 0
+	if s.GoalsBonusThreshold > 0 && goalsFor >= s.GoalsBonusThreshold {
+		bonus += s.GoalsBonusPoints
+	}
+	if s.LosingBonusMargin > 0 && goalsFor < goalsAgainst && goalsAgainst-goalsFor <= s.LosingBonusMargin {
+		bonus += s.LosingBonusPoints
+	}
+	return bonus
+}
+
+// MatchPoints returns everything a side earns from one match
+func (s ScoringRules) MatchPoints(goalsFor int, goalsAgainst int) int {
+	return s.ResultPoints(goalsFor, goalsAgainst) + s.BonusPoints(goalsFor, goalsAgainst)
+}
+
+// PointDeduction is a sanction removing points from a team for a season
+type PointDeduction struct {
+	ID       int
+	SeasonID int
+	TeamID   int
+	Points   int
+	Reason   string
+}
+
+// GetScoringRules returns a league's rules, or the defaults if none are stored
+func (r SQLiteLeagueRepository) GetScoringRules(leagueID int) (ScoringRules, error) {
+	db := storage.GetDB()
+	row := db.QueryRow(`SELECT win_points, draw_points, loss_points, goals_bonus_threshold, goals_bonus_points, losing_bonus_margin, losing_bonus_points
+		FROM scoring_rules WHERE league_id = ?`, leagueID)
+	var s ScoringRules
+	err := row.Scan(&s.WinPoints, &s.DrawPoints, &s.LossPoints, &s.GoalsBonusThreshold, &s.GoalsBonusPoints, &s.LosingBonusMargin, &s.LosingBonusPoints)
+	if err == sql.ErrNoRows {
+		return DefaultScoringRules, nil
+	}
+	return s, err
+}
+
+// UpdateScoringRules stores a league's rules
+func (r SQLiteLeagueRepository) UpdateScoringRules(leagueID int, s ScoringRules) error {
+	db := storage.GetDB()
+	_, err := db.Exec(`INSERT INTO scoring_rules (league_id, win_points, draw_points, loss_points, goals_bonus_threshold, goals_bonus_points, losing_bonus_margin, losing_bonus_points)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(league_id) DO UPDATE SET win_points = excluded.win_points, draw_points = excluded.draw_points, loss_points = excluded.loss_points,
+			goals_bonus_threshold = excluded.goals_bonus_threshold, goals_bonus_points = excluded.goals_bonus_points,
+			losing_bonus_margin = excluded.losing_bonus_margin, losing_bonus_points = excluded.losing_bonus_points`,
+		leagueID, s.WinPoints, s.DrawPoints, s.LossPoints, s.GoalsBonusThreshold, s.GoalsBonusPoints, s.LosingBonusMargin, s.LosingBonusPoints)
+	return err
+}
+
+// GetDeductions returns the point deductions applied in a season
+func (r SQLiteLeagueRepository) GetDeductions(seasonID int) ([]PointDeduction, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT id, season_id, team_id, points, reason FROM point_deductions WHERE season_id = ? ORDER BY id", seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var deductions []PointDeduction
+	for rows.Next() {
+		var d PointDeduction
+		if err := rows.Scan(&d.ID, &d.SeasonID, &d.TeamID, &d.Points, &d.Reason); err != nil {
+			return nil, err
+		}
+		deductions = append(deductions, d)
+	}
+	return deductions, rows.Err()
+}
+
+// CreateDeduction records a sanction, takes the points off the stored
+// standings and returns its ID
+func (r SQLiteLeagueRepository) CreateDeduction(d PointDeduction) (int, error) {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("INSERT INTO point_deductions (season_id, team_id, points, reason) VALUES (?, ?, ?, ?)", d.SeasonID, d.TeamID, d.Points, d.Reason)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := adjustStanding(tx, d.SeasonID, d.TeamID, LeagueTableEntry{Points: -d.Points, PointsDeducted: d.Points}); err != nil {
+		return 0, err
+	}
+	return int(id), tx.Commit()
+}
+
+// DeleteDeduction removes a sanction from a season, returning ErrNotFound
+// if it does not belong to that season
+func (r SQLiteLeagueRepository) DeleteDeduction(seasonID int, id int) error {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var d PointDeduction
+	row := tx.QueryRow("SELECT team_id, points FROM point_deductions WHERE id = ? AND season_id = ?", id, seasonID)
+	if err := row.Scan(&d.TeamID, &d.Points); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM point_deductions WHERE id = ?", id); err != nil {
+		return err
+	}
+	if err := adjustStanding(tx, seasonID, d.TeamID, LeagueTableEntry{Points: d.Points, PointsDeducted: -d.Points}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
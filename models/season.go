@@ -0,0 +1,170 @@
+package models
+
+import (
+	"Case_study/storage"
+	"database/sql"
+)
+
+// Season groups one full schedule of matches; closed seasons keep their
+// results and final standings as an archive
+type Season struct {
+	ID       int
+	LeagueID int
+	Name     string
+	Closed   bool
+}
+
+// ArchivedStanding is a row of a closed season's final table
+type ArchivedStanding struct {
+	Position int
+	LeagueTableEntry
+}
+
+// SQLiteSeasonRepository implements DB operations for seasons
+type SQLiteSeasonRepository struct{}
+
+// GetCurrentSeason returns a league's most recent season that has not been closed
+func (r SQLiteSeasonRepository) GetCurrentSeason(leagueID int) (Season, error) {
+	db := storage.GetDB()
+	row := db.QueryRow("SELECT id, league_id, name, closed FROM seasons WHERE league_id = ? AND closed = 0 ORDER BY id DESC LIMIT 1", leagueID)
+	var s Season
+	if err := row.Scan(&s.ID, &s.LeagueID, &s.Name, &s.Closed); err != nil {
+		if err == sql.ErrNoRows {
+			return s, ErrNoActiveSeason
+		}
+		return s, err
+	}
+	return s, nil
+}
+
+// GetSeason returns a season by ID or ErrNotFound
+func (r SQLiteSeasonRepository) GetSeason(id int) (Season, error) {
+	db := storage.GetDB()
+	row := db.QueryRow("SELECT id, league_id, name, closed FROM seasons WHERE id = ?", id)
+	var s Season
+	if err := row.Scan(&s.ID, &s.LeagueID, &s.Name, &s.Closed); err != nil {
+		if err == sql.ErrNoRows {
+			return s, ErrNotFound
+		}
+		return s, err
+	}
+	return s, nil
+}
+
+func (r SQLiteSeasonRepository) GetAllSeasons() ([]Season, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT id, league_id, name, closed FROM seasons ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	return scanSeasons(rows)
+}
+
+// GetSeasonsByLeague returns a league's seasons, oldest first
+func (r SQLiteSeasonRepository) GetSeasonsByLeague(leagueID int) ([]Season, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT id, league_id, name, closed FROM seasons WHERE league_id = ? ORDER BY id", leagueID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSeasons(rows)
+}
+
+func scanSeasons(rows *sql.Rows) ([]Season, error) {
+	defer rows.Close()
+	var seasons []Season
+	for rows.Next() {
+		var s Season
+		if err := rows.Scan(&s.ID, &s.LeagueID, &s.Name, &s.Closed); err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, s)
+	}
+	return seasons, rows.Err()
+}
+
+// CreateSeason opens a new season in a league and returns its ID
+func (r SQLiteSeasonRepository) CreateSeason(leagueID int, name string) (int, error) {
+	return createSeasonTx(storage.GetDB(), leagueID, name)
+}
+
+func createSeasonTx(tx storage.DBTX, leagueID int, name string) (int, error) {
+	res, err := tx.Exec("INSERT INTO seasons (league_id, name, closed) VALUES (?, ?, 0)", leagueID, name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// CloseSeason marks a season closed and stores its final table in order
+func (r SQLiteSeasonRepository) CloseSeason(id int, table []LeagueTableEntry) error {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := closeSeasonTx(tx, id, table); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func closeSeasonTx(tx storage.DBTX, id int, table []LeagueTableEntry) error {
+	if err := writeStandings(tx, id, table); err != nil {
+		return err
+	}
+	_, err := tx.Exec("UPDATE seasons SET closed = 1 WHERE id = ?", id)
+	return err
+}
+
+// StartNextSeason closes a season with its final table and opens the
+// league's next one with its fixtures and the ratings it starts from, all
+// in one transaction, and returns the new season's ID. If any step fails
+// the old season stays open and no new one is created.
+func (r SQLiteSeasonRepository) StartNextSeason(closingID int, table []LeagueTableEntry, leagueID int, name string, fixtures []Match, teams []Team, start []RatingPoint) (int, error) {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if err := closeSeasonTx(tx, closingID, table); err != nil {
+		return 0, err
+	}
+	seasonID, err := createSeasonTx(tx, leagueID, name)
+	if err != nil {
+		return 0, err
+	}
+	if err := replaceSeasonMatchesTx(tx, seasonID, fixtures); err != nil {
+		return 0, err
+	}
+	if err := saveRatingsTx(tx, seasonID, teams, start); err != nil {
+		return 0, err
+	}
+	return seasonID, tx.Commit()
+}
+
+// GetArchivedTable returns the final standings stored when a season closed
+func (r SQLiteSeasonRepository) GetArchivedTable(id int) ([]ArchivedStanding, error) {
+	db := storage.GetDB()
+	rows, err := db.Query(`SELECT l.position, l.team_id, t.name, l.points, l.goals_for, l.goals_against, l.goal_difference, l.matches_played,
+		l.wins, l.draws, l.losses, l.away_goals_for, l.points_deducted
+		FROM league_table l JOIN teams t ON l.team_id = t.id
+		WHERE l.season_id = ? ORDER BY l.position`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var table []ArchivedStanding
+	for rows.Next() {
+		var s ArchivedStanding
+		if err := rows.Scan(&s.Position, &s.TeamID, &s.TeamName, &s.Points, &s.GoalsFor, &s.GoalsAgainst, &s.GoalDifference, &s.MatchesPlayed,
+			&s.Wins, &s.Draws, &s.Losses, &s.AwayGoalsFor, &s.PointsDeducted); err != nil {
+			return nil, err
+		}
+		table = append(table, s)
+	}
+	return table, rows.Err()
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+)
+
+// SquadMatchSimulator derives each side's expected goals from the players
+// it fields rather than from the team's strength, so a weakened lineup
+// plays like one. A side's attack is set against the opponent's defence,
+// both scaled by the team's StrengthFactor; AvailabilityFactor is left out
+// because missing players are already out of the lineup. Teams without a
+// full squad fall back to the Poisson model.
+type SquadMatchSimulator struct {
+	PoissonMatchSimulator
+}
+
+// NewSquadMatchSimulator returns a squad simulator with the Poisson
+// defaults
+func NewSquadMatchSimulator() SquadMatchSimulator {
+	return SquadMatchSimulator{NewPoissonMatchSimulator()}
+}
+
+// ExpectedGoals returns the Poisson means for the home and away side,
+// capped at MaxExpectedGoals
+func (s SquadMatchSimulator) ExpectedGoals(home Team, away Team) (float64, float64) {
+	homeAttack, homeDefence, homeOK := LineupStrength(StartingEleven(home.Players))
+	awayAttack, awayDefence, awayOK := LineupStrength(StartingEleven(away.Players))
+	if !homeOK || !awayOK {
+		return s.PoissonMatchSimulator.ExpectedGoals(home, away)
+	}
+	homeAttack, homeDefence = homeAttack*home.strengthFactor(), homeDefence*home.strengthFactor()
+	awayAttack, awayDefence = awayAttack*away.strengthFactor(), awayDefence*away.strengthFactor()
+	advantage := s.HomeAdvantage
+	if advantage <= 0 {
+		advantage = 1
+	}
+	homeXG := s.AverageGoals * advantage * homeAttack / math.Max(awayDefence, 1)
+	awayXG := s.AverageGoals / advantage * awayAttack / math.Max(homeDefence, 1)
+	return capExpectedGoals(homeXG), capExpectedGoals(awayXG)
+}
+
+// SimulateMatch returns simulated goals for home and away teams
+func (s SquadMatchSimulator) SimulateMatch(home Team, away Team) (int, int) {
+	return s.SimulateMatchWithRand(home, away, newRand())
+}
+
+// SimulateMatchWithRand is SimulateMatch drawing from the given generator
+func (s SquadMatchSimulator) SimulateMatchWithRand(home Team, away Team, rng *rand.Rand) (int, int) {
+	homeXG, awayXG := s.ExpectedGoals(home, away)
+	return samplePoisson(homeXG, rng), samplePoisson(awayXG, rng)
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"Case_study/storage"
+)
+
+// SQLiteStandingsRepository keeps the league_table rows of the season in
+// progress in step with match results, so tables can be read without
+// replaying every match
+type SQLiteStandingsRepository struct{}
+
+// GetStandings returns the stored rows for every team of the season's league
+// in no particular order; teams without a row yet get an empty entry
+func (r SQLiteStandingsRepository) GetStandings(seasonID int) ([]LeagueTableEntry, error) {
+	db := storage.GetDB()
+	rows, err := db.Query(`SELECT t.id, t.name, COALESCE(l.points, 0), COALESCE(l.goals_for, 0), COALESCE(l.goals_against, 0),
+		COALESCE(l.goal_difference, 0), COALESCE(l.matches_played, 0), COALESCE(l.wins, 0), COALESCE(l.draws, 0),
+		COALESCE(l.losses, 0), COALESCE(l.away_goals_for, 0), COALESCE(l.points_deducted, 0)
+		FROM teams t
+		JOIN seasons s ON s.league_id = t.league_id
+		LEFT JOIN league_table l ON l.team_id = t.id AND l.season_id = s.id
+		WHERE s.id = ?`, seasonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var table []LeagueTableEntry
+	for rows.Next() {
+		var e LeagueTableEntry
+		if err := rows.Scan(&e.TeamID, &e.TeamName, &e.Points, &e.GoalsFor, &e.GoalsAgainst, &e.GoalDifference,
+			&e.MatchesPlayed, &e.Wins, &e.Draws, &e.Losses, &e.AwayGoalsFor, &e.PointsDeducted); err != nil {
+			return nil, err
+		}
+		table = append(table, e)
+	}
+	return table, rows.Err()
+}
+
+// Rebuild replaces a season's stored rows with a freshly calculated table
+func (r SQLiteStandingsRepository) Rebuild(seasonID int, table []LeagueTableEntry) error {
+	db := storage.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := writeStandings(tx, seasonID, table); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// writeStandings stores a ranked table, numbering positions in order
+func writeStandings(tx storage.DBTX, seasonID int, table []LeagueTableEntry) error {
+	if _, err := tx.Exec("DELETE FROM league_table WHERE season_id = ?", seasonID); err != nil {
+		return err
+	}
+	for i, e := range table {
+		_, err := tx.Exec("INSERT INTO league_table (season_id, team_id, position, points, goals_for, goals_against, goal_difference, matches_played, wins, draws, losses, away_goals_for, points_deducted) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			seasonID, e.TeamID, i+1, e.Points, e.GoalsFor, e.GoalsAgainst, e.GoalDifference, e.MatchesPlayed, e.Wins, e.Draws, e.Losses, e.AwayGoalsFor, e.PointsDeducted)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adjustStanding adds delta to a team's stored row, creating it if needed
+func adjustStanding(tx storage.DBTX, seasonID int, teamID int, delta LeagueTableEntry) error {
+	_, err := tx.Exec(`INSERT INTO league_table (season_id, team_id, points, goals_for, goals_against, goal_difference, matches_played)
+		VALUES (?, ?, 0, 0, 0, 0, 0) ON CONFLICT(season_id, team_id) DO NOTHING`, seasonID, teamID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE league_table SET points = points + ?, goals_for = goals_for + ?, goals_against = goals_against + ?,
+		goal_difference = goal_difference + ?, matches_played = matches_played + ?, wins = wins + ?, draws = draws + ?,
+		losses = losses + ?, away_goals_for = away_goals_for + ?, points_deducted = points_deducted + ?
+		WHERE season_id = ? AND team_id = ?`,
+		delta.Points, delta.GoalsFor, delta.GoalsAgainst, delta.GoalDifference, delta.MatchesPlayed, delta.Wins, delta.Draws,
+		delta.Losses, delta.AwayGoalsFor, delta.PointsDeducted, seasonID, teamID)
+	return err
+}
+
+// applyResult adds a result to both teams' rows, or removes it when sign
+// is -1
+func applyResult(tx storage.DBTX, rules ScoringRules, m Match, sign int) error {
+	if !m.Status.CountsInTable() || !m.HomeGoals.Valid || !m.AwayGoals.Valid {
+		return nil
+	}
+	hg, ag := int(m.HomeGoals.Int64), int(m.AwayGoals.Int64)
+	home := resultDelta(rules, hg, ag, sign)
+	away := resultDelta(rules, ag, hg, sign)
+	away.AwayGoalsFor = sign * ag
+	if err := adjustStanding(tx, m.SeasonID, m.HomeTeamID, home); err != nil {
+		return err
+	}
+	return adjustStanding(tx, m.SeasonID, m.AwayTeamID, away)
+}
+
+// resultDelta is one side's contribution from a single match
+func resultDelta(rules ScoringRules, goalsFor int, goalsAgainst int, sign int) LeagueTableEntry {
+	d := LeagueTableEntry{
+		Points:         sign * rules.MatchPoints(goalsFor, goalsAgainst),
+		GoalsFor:       sign * goalsFor,
+		GoalsAgainst:   sign * goalsAgainst,
+		GoalDifference: sign * (goalsFor - goalsAgainst),
+		MatchesPlayed:  sign,
+	}
+	if goalsFor > goalsAgainst {
+		d.Wins = sign
+	} else if goalsFor < goalsAgainst {
+		d.Losses = sign
+	} else {
+		d.Draws = sign
+	}
+	return d
+}
+
+// seasonScoringRules looks up the rules of the league a season belongs to
+func seasonScoringRules(seasonID int) (ScoringRules, error) {
+	season, err := SQLiteSeasonRepository{}.GetSeason(seasonID)
+	if err != nil {
+		return ScoringRules{}, err
+	}
+	return SQLiteLeagueRepository{}.GetScoringRules(season.LeagueID)
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// MatchStatus tracks where a match is in its lifecycle
+type MatchStatus string
+
+const (
+	StatusScheduled MatchStatus = "scheduled"
+	StatusLive      MatchStatus = "live"
+	StatusCompleted MatchStatus = "completed"
+	StatusPostponed MatchStatus = "postponed"
+	StatusAbandoned MatchStatus = "abandoned"
+	// StatusAwarded is an administrative result, such as a 3-0 forfeit
+	StatusAwarded MatchStatus = "awarded"
+)
+
+// AllMatchStatuses lists every status
+var AllMatchStatuses = []MatchStatus{
+	StatusScheduled,
+	StatusLive,
+	StatusCompleted,
+	StatusPostponed,
+	StatusAbandoned,
+	StatusAwarded,
+}
+
+// matchTransitions lists the statuses each status may move to. Any match
+// can go back to scheduled, which is how results are reverted and
+// postponed or abandoned matches are rearranged.
+var matchTransitions = map[MatchStatus][]MatchStatus{
+	StatusScheduled: {StatusLive, StatusCompleted, StatusPostponed, StatusAwarded},
+	StatusLive:      {StatusCompleted, StatusAbandoned, StatusScheduled},
+	StatusCompleted: {StatusAwarded, StatusScheduled},
+	StatusPostponed: {StatusLive, StatusCompleted, StatusAwarded, StatusScheduled},
+	StatusAbandoned: {StatusLive, StatusCompleted, StatusAwarded, StatusScheduled},
+	StatusAwarded:   {StatusCompleted, StatusScheduled},
+}
+
+// Valid reports whether the status is known
+func (s MatchStatus) Valid() bool {
+	_, ok := matchTransitions[s]
+	return ok
+}
+
+// CountsInTable reports whether a match with this status has a result
+// that counts towards the standings
+func (s MatchStatus) CountsInTable() bool {
+	return s == StatusCompleted || s == StatusAwarded
+}
+
+// CanTransitionTo reports whether a match may move from s to next. Staying
+// in the same status is always allowed, so a result can be corrected.
+func (s MatchStatus) CanTransitionTo(next MatchStatus) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range matchTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTransition returns ErrInvalidTransition wrapped with both statuses
+// when a match may not move from old to next
+func checkTransition(matchID int, old MatchStatus, next MatchStatus) error {
+	if !next.Valid() {
+		return fmt.Errorf("%w: match %d: unknown status %q", ErrInvalidTransition, matchID, next)
+	}
+	if !old.CanTransitionTo(next) {
+		return fmt.Errorf("%w: match %d cannot go from %s to %s", ErrInvalidTransition, matchID, old, next)
+	}
+	return nil
+}
+
+// SetResult records a score played on the pitch
+func (m *Match) SetResult(homeGoals int, awayGoals int) {
+	m.HomeGoals = sql.NullInt64{Int64: int64(homeGoals), Valid: true}
+	m.AwayGoals = sql.NullInt64{Int64: int64(awayGoals), Valid: true}
+	m.SetStatus(StatusCompleted)
+}
+
+// Award records an administrative result
+func (m *Match) Award(homeGoals int, awayGoals int) {
+	m.SetResult(homeGoals, awayGoals)
+	m.SetStatus(StatusAwarded)
+}
+
+// SetStatus moves the match to a status and keeps Played in step with it.
+// Scheduled and postponed matches lose any score and events; a match going
+// live starts at 0-0 with an empty timeline and an abandoned one keeps the
+// score it was stopped at.
+func (m *Match) SetStatus(status MatchStatus) {
+	previous := m.Status
+	m.Status = status
+	m.Played = status.CountsInTable()
+	switch status {
+	case StatusScheduled, StatusPostponed:
+		m.HomeGoals = sql.NullInt64{}
+		m.AwayGoals = sql.NullInt64{}
+		m.Events = []MatchEvent{}
+	case StatusLive:
+		if previous != StatusLive {
+			m.HomeGoals = sql.NullInt64{Int64: 0, Valid: true}
+			m.AwayGoals = sql.NullInt64{Int64: 0, Valid: true}
+			m.Events = []MatchEvent{}
+		}
+	}
+}
+
+// SetLiveScore updates the running score of a match in progress
+func (m *Match) SetLiveScore(homeGoals int, awayGoals int) {
+	m.HomeGoals = sql.NullInt64{Int64: int64(homeGoals), Valid: true}
+	m.AwayGoals = sql.NullInt64{Int64: int64(awayGoals), Valid: true}
+}
@@ -3,70 +3,212 @@ package models
 import (
 	"Case_study/storage"
 	"database/sql"
+	"fmt"
+	"strings"
 )
 
 // Team represents a football team in the league
  type Team struct {
     ID            int
+    LeagueID      int
     Name          string
     Strength      int
+    Rating        float64
     Points        int
     GoalsFor      int
     GoalsAgainst  int
     GoalDifference int
     MatchesPlayed int
+    // FairPlayPoints accumulates disciplinary points; fewer is better
+    FairPlayPoints int
+    // Players is the squad; GetLeague loads it, other queries leave it empty
+    Players       []Player
+    // StrengthFactor scales the team's strength for one simulation, for
+    // example by form; zero means unchanged
+    StrengthFactor float64
+    // AvailabilityFactor scales the team's strength for the starters it is
+    // missing through injury or suspension; zero means none are missing
+    AvailabilityFactor float64
+}
+
+// CurrentStrength returns the strength simulators should use: the team's
+// Elo rating once one has been computed, otherwise its seed strength,
+// scaled by StrengthFactor and AvailabilityFactor
+func (t Team) CurrentStrength() float64 {
+	strength := float64(t.Strength)
+	if t.Rating > 0 {
+		strength = StrengthFromRating(t.Rating)
+	}
+	return strength * t.factor()
+}
+
+// factor returns StrengthFactor times AvailabilityFactor, treating zero as
+// one
+func (t Team) factor() float64 {
+	if t.AvailabilityFactor > 0 {
+		return t.strengthFactor() * t.AvailabilityFactor
+	}
+	return t.strengthFactor()
+}
+
+// strengthFactor returns StrengthFactor, treating zero as one
+func (t Team) strengthFactor() float64 {
+	if t.StrengthFactor > 0 {
+		return t.StrengthFactor
+	}
+	return 1
+}
+
+// Validate rejects teams without a name or with a strength out of range
+func (t Team) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("team name is required")
+	}
+	if t.Strength < MinStrength || t.Strength > MaxStrength {
+		return fmt.Errorf("strength must be between %d and %d", MinStrength, MaxStrength)
+	}
+	return nil
 }
 
 // TeamRepository defines DB operations for teams
  type TeamRepository interface {
     GetAllTeams() ([]Team, error)
+    GetTeamsByLeague(leagueID int) ([]Team, error)
     GetTeamByID(id int) (Team, error)
     UpdateTeam(team Team) error
-    CreateTeam(team Team) error
+    CreateTeam(team Team) (int, error)
+    DeleteTeam(id int) error
 }
 
 // SQLiteTeamRepository implements TeamRepository using SQLite
  type SQLiteTeamRepository struct{}
 
-func (r SQLiteTeamRepository) GetAllTeams() ([]Team, error) {
-	db := storage.GetDB()
-	rows, err := db.Query("SELECT id, name, strength FROM teams")
-	if err != nil {
-		return nil, err
-	}
+const teamColumns = "id, league_id, name, strength, COALESCE(rating, 0), fair_play_points"
+
+func scanTeams(rows *sql.Rows) ([]Team, error) {
 	defer rows.Close()
 	var teams []Team
 	for rows.Next() {
 		var t Team
-		if err := rows.Scan(&t.ID, &t.Name, &t.Strength); err != nil {
+		if err := rows.Scan(&t.ID, &t.LeagueID, &t.Name, &t.Strength, &t.Rating, &t.FairPlayPoints); err != nil {
 			return nil, err
 		}
 		teams = append(teams, t)
 	}
-	return teams, nil
+	return teams, rows.Err()
+}
+
+func (r SQLiteTeamRepository) GetAllTeams() ([]Team, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT " + teamColumns + " FROM teams")
+	if err != nil {
+		return nil, err
+	}
+	return scanTeams(rows)
 }
 
+// GetTeamsByLeague returns the teams competing in one league
+func (r SQLiteTeamRepository) GetTeamsByLeague(leagueID int) ([]Team, error) {
+	db := storage.GetDB()
+	rows, err := db.Query("SELECT "+teamColumns+" FROM teams WHERE league_id = ?", leagueID)
+	if err != nil {
+		return nil, err
+	}
+	return scanTeams(rows)
+}
+
+// GetTeamByID returns ErrNotFound if the team does not exist
 func (r SQLiteTeamRepository) GetTeamByID(id int) (Team, error) {
 	db := storage.GetDB()
-	row := db.QueryRow("SELECT id, name, strength FROM teams WHERE id = ?", id)
+	row := db.QueryRow("SELECT "+teamColumns+" FROM teams WHERE id = ?", id)
 	var t Team
-	if err := row.Scan(&t.ID, &t.Name, &t.Strength); err != nil {
+	if err := row.Scan(&t.ID, &t.LeagueID, &t.Name, &t.Strength, &t.Rating, &t.FairPlayPoints); err != nil {
 		if err == sql.ErrNoRows {
-			return t, nil
+			return t, ErrNotFound
 		}
 		return t, err
 	}
 	return t, nil
 }
 
+// UpdateTeam returns ErrNotFound if the team does not exist
 func (r SQLiteTeamRepository) UpdateTeam(team Team) error {
 	db := storage.GetDB()
-	_, err := db.Exec("UPDATE teams SET name = ?, strength = ? WHERE id = ?", team.Name, team.Strength, team.ID)
-	return err
+	res, err := db.Exec("UPDATE teams SET name = ?, strength = ?, fair_play_points = ? WHERE id = ?", team.Name, team.Strength, team.FairPlayPoints, team.ID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateTeam inserts a team and returns its ID
+func (r SQLiteTeamRepository) CreateTeam(team Team) (int, error) {
+	return createTeamTx(storage.GetDB(), team)
+}
+
+func createTeamTx(tx storage.DBTX, team Team) (int, error) {
+	// A zero ID lets SQLite assign the next free one
+	var id interface{}
+	if team.ID != 0 {
+		id = team.ID
+	}
+	res, err := tx.Exec("INSERT INTO teams (id, league_id, name, strength) VALUES (?, ?, ?, ?)", id, team.LeagueID, team.Name, team.Strength)
+	if err != nil {
+		return 0, err
+	}
+	newID, err := res.LastInsertId()
+	return int(newID), err
 }
 
-func (r SQLiteTeamRepository) CreateTeam(team Team) error {
+// DeleteTeam removes a team together with its fixtures, ratings, standings
+// and deductions. It returns ErrTeamHasPlayed if any of its matches has
+// started, that is it is no longer scheduled (played, live, postponed, ...)
+// or has recorded events, since those belong to other teams too.
+func (r SQLiteTeamRepository) DeleteTeam(id int) error {
 	db := storage.GetDB()
-	_, err := db.Exec("INSERT INTO teams (id, name, strength) VALUES (?, ?, ?)", team.ID, team.Name, team.Strength)
-	return err
-} 
\ No newline at end of file
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM teams WHERE id = ?", id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrNotFound
+	}
+	var started int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM matches m WHERE (m.home_team_id = ? OR m.away_team_id = ?)
+		AND (m.status != ? OR EXISTS (SELECT 1 FROM match_events e WHERE e.match_id = m.id))`, id, id, StatusScheduled).Scan(&started); err != nil {
+		return err
+	}
+	if started > 0 {
+		return ErrTeamHasPlayed
+	}
+	if _, err := tx.Exec("DELETE FROM match_events WHERE match_id IN (SELECT id FROM matches WHERE home_team_id = ? OR away_team_id = ?)", id, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM matches WHERE home_team_id = ? OR away_team_id = ?", id, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM player_absences WHERE player_id IN (SELECT id FROM players WHERE team_id = ?)", id); err != nil {
+		return err
+	}
+	for _, table := range []string{"team_ratings", "league_table", "point_deductions", "players"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE team_id = ?", id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM teams WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
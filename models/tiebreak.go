@@ -0,0 +1,199 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TieBreaker names one criterion used to order teams level on points
+type TieBreaker string
+
+const (
+	TieBreakGoalDifference           TieBreaker = "goal_difference"
+	TieBreakGoalsFor                 TieBreaker = "goals_for"
+	TieBreakHeadToHeadPoints         TieBreaker = "head_to_head_points"
+	TieBreakHeadToHeadGoalDifference TieBreaker = "head_to_head_goal_difference"
+	TieBreakAwayGoals                TieBreaker = "away_goals"
+	TieBreakWins                     TieBreaker = "wins"
+	TieBreakFairPlay                 TieBreaker = "fair_play"
+	TieBreakLots                     TieBreaker = "lots"
+	TieBreakName                     TieBreaker = "name"
+)
+
+// AllTieBreakers lists every supported criterion
+var AllTieBreakers = []TieBreaker{
+	TieBreakGoalDifference,
+	TieBreakGoalsFor,
+	TieBreakHeadToHeadPoints,
+	TieBreakHeadToHeadGoalDifference,
+	TieBreakAwayGoals,
+	TieBreakWins,
+	TieBreakFairPlay,
+	TieBreakLots,
+	TieBreakName,
+}
+
+// DefaultTieBreakers orders teams on goal difference, goals scored and
+// finally name
+var DefaultTieBreakers = []TieBreaker{TieBreakGoalDifference, TieBreakGoalsFor, TieBreakName}
+
+// ParseTieBreakers reads a comma separated list of criteria
+func ParseTieBreakers(s string) ([]TieBreaker, error) {
+	var order []TieBreaker
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tb := TieBreaker(part)
+		if !tb.Valid() {
+			return nil, fmt.Errorf("unknown tie-breaker %q", part)
+		}
+		order = append(order, tb)
+	}
+	return order, nil
+}
+
+// FormatTieBreakers is the inverse of ParseTieBreakers
+func FormatTieBreakers(order []TieBreaker) string {
+	parts := make([]string, len(order))
+	for i, tb := range order {
+		parts[i] = string(tb)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Valid reports whether the criterion is supported
+func (tb TieBreaker) Valid() bool {
+	for _, known := range AllTieBreakers {
+		if tb == known {
+			return true
+		}
+	}
+	return false
+}
+
+// TieBreakRules configures how a league separates teams level on points
+type TieBreakRules struct {
+	Order []TieBreaker
+	// LotsSeed makes the drawing of lots reproducible
+	LotsSeed int64
+	// FairPlay holds each team's disciplinary points; fewer ranks higher
+	FairPlay map[int]int
+	// Scoring values results for the head-to-head points criterion
+	Scoring ScoringRules
+}
+
+// RankTable sorts table entries by points and then applies the tie-breaker
+// chain to every group of teams still level. Head-to-head criteria only
+// count matches between the teams in the group being separated. Teams that
+// remain level after the whole chain are ordered by ID so the result is
+// always deterministic.
+func RankTable(table []LeagueTableEntry, matches []Match, rules TieBreakRules) []LeagueTableEntry {
+	ranked := make([]LeagueTableEntry, len(table))
+	copy(ranked, table)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Points > ranked[j].Points
+	})
+	for start := 0; start < len(ranked); {
+		end := start + 1
+		for end < len(ranked) && ranked[end].Points == ranked[start].Points {
+			end++
+		}
+		breakTies(ranked[start:end], matches, rules, rules.Order)
+		start = end
+	}
+	return ranked
+}
+
+// breakTies orders a group of level teams in place using the first
+// criterion, then recurses into any sub-groups that are still level
+func breakTies(group []LeagueTableEntry, matches []Match, rules TieBreakRules, order []TieBreaker) {
+	if len(group) < 2 {
+		return
+	}
+	if len(order) == 0 {
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].TeamID < group[j].TeamID
+		})
+		return
+	}
+	criterion := order[0]
+	keys := tieBreakKeys(group, matches, rules, criterion)
+	sort.SliceStable(group, func(i, j int) bool {
+		return keys[group[i].TeamID] > keys[group[j].TeamID]
+	})
+	for start := 0; start < len(group); {
+		end := start + 1
+		for end < len(group) && keys[group[end].TeamID] == keys[group[start].TeamID] {
+			end++
+		}
+		breakTies(group[start:end], matches, rules, order[1:])
+		start = end
+	}
+}
+
+// tieBreakKeys scores every team in the group for one criterion; a higher
+// key ranks higher
+func tieBreakKeys(group []LeagueTableEntry, matches []Match, rules TieBreakRules, criterion TieBreaker) map[int]int64 {
+	keys := make(map[int]int64)
+	switch criterion {
+	case TieBreakGoalDifference:
+		for _, e := range group {
+			keys[e.TeamID] = int64(e.GoalDifference)
+		}
+	case TieBreakGoalsFor:
+		for _, e := range group {
+			keys[e.TeamID] = int64(e.GoalsFor)
+		}
+	case TieBreakAwayGoals:
+		for _, e := range group {
+			keys[e.TeamID] = int64(e.AwayGoalsFor)
+		}
+	case TieBreakWins:
+		for _, e := range group {
+			keys[e.TeamID] = int64(e.Wins)
+		}
+	case TieBreakFairPlay:
+		for _, e := range group {
+			keys[e.TeamID] = -int64(rules.FairPlay[e.TeamID])
+		}
+	case TieBreakName:
+		// Alphabetical order: earlier names get higher keys
+		names := make([]string, 0, len(group))
+		for _, e := range group {
+			names = append(names, e.TeamName)
+		}
+		sort.Strings(names)
+		for _, e := range group {
+			keys[e.TeamID] = -int64(sort.SearchStrings(names, e.TeamName))
+		}
+	case TieBreakLots:
+		// Each team's lot depends only on the seed and its ID, so the draw
+		// does not change with the order teams are compared in
+		for _, e := range group {
+			keys[e.TeamID] = int64(splitMix64(uint64(rules.LotsSeed)^splitMix64(uint64(e.TeamID))) >> 1)
+		}
+	case TieBreakHeadToHeadPoints, TieBreakHeadToHeadGoalDifference:
+		inGroup := make(map[int]bool)
+		for _, e := range group {
+			inGroup[e.TeamID] = true
+			keys[e.TeamID] = 0
+		}
+		for _, m := range matches {
+			if !m.Status.CountsInTable() || !inGroup[m.HomeTeamID] || !inGroup[m.AwayTeamID] {
+				continue
+			}
+			hg, ag := int64(m.HomeGoals.Int64), int64(m.AwayGoals.Int64)
+			if criterion == TieBreakHeadToHeadGoalDifference {
+				keys[m.HomeTeamID] += hg - ag
+				keys[m.AwayTeamID] += ag - hg
+				continue
+			}
+			keys[m.HomeTeamID] += int64(rules.Scoring.ResultPoints(int(hg), int(ag)))
+			keys[m.AwayTeamID] += int64(rules.Scoring.ResultPoints(int(ag), int(hg)))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,72 @@
+package models
+
+import "testing"
+
+// result returns a completed match between two teams
+func result(id, home, away, homeGoals, awayGoals int) Match {
+	m := Match{ID: id, Week: id, HomeTeamID: home, AwayTeamID: away}
+	m.SetResult(homeGoals, awayGoals)
+	return m
+}
+
+func TestRankTableHeadToHead(t *testing.T) {
+	// Teams 1, 2 and 3 are level on points. Team 1 has the best goal
+	// difference but lost both games against team 2, and team 3 beat team 2.
+	table := []LeagueTableEntry{
+		{TeamID: 1, TeamName: "Ajax", Points: 10, GoalDifference: 8},
+		{TeamID: 2, TeamName: "Benfica", Points: 10, GoalDifference: 3},
+		{TeamID: 3, TeamName: "Celtic", Points: 10, GoalDifference: 1},
+		{TeamID: 4, TeamName: "Dynamo", Points: 4, GoalDifference: -12},
+	}
+	matches := []Match{
+		result(1, 2, 1, 1, 0),
+		result(2, 1, 2, 0, 2),
+		result(3, 3, 2, 2, 1),
+		result(4, 2, 3, 1, 1),
+		result(5, 1, 3, 1, 1),
+		result(6, 3, 1, 0, 0),
+		// Against a team outside the group, so head-to-head ignores it
+		result(7, 1, 4, 5, 0),
+	}
+	tests := []struct {
+		name  string
+		order []TieBreaker
+		want  []int
+	}{
+		{"goal difference", []TieBreaker{TieBreakGoalDifference}, []int{1, 2, 3, 4}},
+		// Head-to-head points: team 2 has 7, team 3 has 6, team 1 has 2
+		{"head-to-head points", []TieBreaker{TieBreakHeadToHeadPoints, TieBreakGoalDifference}, []int{2, 3, 1, 4}},
+		// Head-to-head goal difference: team 2 +2, team 3 +1, team 1 -3
+		{"head-to-head goal difference", []TieBreaker{TieBreakHeadToHeadGoalDifference}, []int{2, 3, 1, 4}},
+		{"name", []TieBreaker{TieBreakName}, []int{1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := RankTable(table, matches, TieBreakRules{Order: tt.order, Scoring: DefaultScoringRules})
+			for i, e := range ranked {
+				if e.TeamID != tt.want[i] {
+					got := []int{}
+					for _, e := range ranked {
+						got = append(got, e.TeamID)
+					}
+					t.Fatalf("order %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// Head-to-head points level within the group fall through to the next
+// criterion, applied only to the teams still level
+func TestRankTableHeadToHeadFallsThrough(t *testing.T) {
+	table := []LeagueTableEntry{
+		{TeamID: 1, TeamName: "Ajax", Points: 6, GoalDifference: 1, GoalsFor: 9},
+		{TeamID: 2, TeamName: "Benfica", Points: 6, GoalDifference: 1, GoalsFor: 7},
+	}
+	matches := []Match{result(1, 1, 2, 2, 2), result(2, 2, 1, 0, 0)}
+	order := []TieBreaker{TieBreakHeadToHeadPoints, TieBreakHeadToHeadGoalDifference, TieBreakGoalsFor}
+	ranked := RankTable(table, matches, TieBreakRules{Order: order, Scoring: DefaultScoringRules})
+	if ranked[0].TeamID != 1 {
+		t.Errorf("team %d first, want team 1 on goals scored", ranked[0].TeamID)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Case_study/models"
+)
+
+const (
+	defaultPredictionSamples = 10000
+	defaultTopScorelines     = 5
+)
+
+// overUnderLines are the goal lines the prediction reports
+var overUnderLines = []float64{0.5, 1.5, 2.5, 3.5, 4.5}
+
+type ScorelineJSON struct {
+	HomeGoals   int     `json:"home_goals"`
+	AwayGoals   int     `json:"away_goals"`
+	Probability float64 `json:"probability"`
+}
+
+type OverUnderJSON struct {
+	Line  float64 `json:"line"`
+	Over  float64 `json:"over"`
+	Under float64 `json:"under"`
+}
+
+func overUnderToJSON(distribution []float64) []OverUnderJSON {
+	lines := []OverUnderJSON{}
+	for _, line := range overUnderLines {
+		over := models.Over(distribution, line)
+		lines = append(lines, OverUnderJSON{Line: line, Over: over, Under: 1 - over})
+	}
+	return lines
+}
+
+// matchPrediction returns the outcome probabilities of a match with the
+// simulator the request selects: 1X2, the ?top= most likely scorelines (default 5),
+// expected goals and over/under lines for each side and in total. They are
+// exact for simulators with a goal model and otherwise sampled ?samples=
+// times (default 10000) from ?seed=. A played match is predicted as it
+// stood before its week, next to the actual result.
+func matchPrediction(w http.ResponseWriter, r *http.Request, match models.Match) {
+	q := r.URL.Query()
+	samples := defaultPredictionSamples
+	if v := q.Get("samples"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSimulations {
+			http.Error(w, "samples must be between 1 and "+strconv.Itoa(maxSimulations), 400)
+			return
+		}
+		samples = n
+	}
+	seed := time.Now().UnixNano()
+	if v := q.Get("seed"); v != "" {
+		s, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid seed", 400)
+			return
+		}
+		seed = s
+	}
+	top := defaultTopScorelines
+	if v := q.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "top must be a positive integer", 400)
+			return
+		}
+		top = n
+	}
+	sim, err := simulatorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	league, i, ok := openMatchLeague(w, match)
+	if !ok {
+		return
+	}
+	m := league.Matches[i]
+	if m.Status.CountsInTable() {
+		if league, err = rewindLeague(league, m.Week-1); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+	}
+	avail, err := models.LoadAvailability(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	teams := avail.Teams(league, m.Week)
+	home, away := getTeamByID(teams, m.HomeTeamID), getTeamByID(teams, m.AwayTeamID)
+	prediction := models.PredictMatch(models.BindSimulator(sim, league), home, away, samples, rand.New(rand.NewSource(seed)))
+
+	homeWin, draw, awayWin := prediction.Outcome()
+	scorelines := []ScorelineJSON{}
+	for _, s := range prediction.TopScorelines(top) {
+		scorelines = append(scorelines, ScorelineJSON{HomeGoals: s.HomeGoals, AwayGoals: s.AwayGoals, Probability: s.Probability})
+	}
+	homeGoals, awayGoals, totalGoals := prediction.GoalDistributions()
+	result := map[string]interface{}{
+		"match":    matchToJSON(match),
+		"home_win": homeWin,
+		"draw":     draw,
+		"away_win": awayWin,
+		"expected_goals": map[string]float64{
+			"home": prediction.HomeXG,
+			"away": prediction.AwayXG,
+		},
+		"scorelines":  scorelines,
+		"total_goals": totalGoals,
+		"over_under": map[string][]OverUnderJSON{
+			"total": overUnderToJSON(totalGoals),
+			"home":  overUnderToJSON(homeGoals),
+			"away":  overUnderToJSON(awayGoals),
+		},
+		"method": "analytic",
+	}
+	if !prediction.Analytic {
+		result["method"] = "sampled"
+		result["samples"] = samples
+		result["seed"] = seed
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
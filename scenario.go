@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"Case_study/models"
+)
+
+// PinnedResultJSON fixes the score of one unplayed match in a scenario
+type PinnedResultJSON struct {
+	MatchID   int `json:"match_id"`
+	HomeGoals int `json:"home_goals"`
+	AwayGoals int `json:"away_goals"`
+}
+
+// pinResults returns a copy of the league with the given scores entered as
+// played results. Only unplayed matches of the league can be pinned.
+func pinResults(league models.League, pinned []PinnedResultJSON) (models.League, error) {
+	league.Matches = append([]models.Match(nil), league.Matches...)
+	index := make(map[int]int)
+	for i, m := range league.Matches {
+		index[m.ID] = i
+	}
+	seen := make(map[int]bool)
+	for _, p := range pinned {
+		i, ok := index[p.MatchID]
+		if !ok {
+			return league, fmt.Errorf("match %d is not part of the season", p.MatchID)
+		}
+		if league.Matches[i].Played {
+			return league, fmt.Errorf("match %d has already been played", p.MatchID)
+		}
+		if seen[p.MatchID] {
+			return league, fmt.Errorf("match %d is pinned more than once", p.MatchID)
+		}
+		if p.HomeGoals < 0 || p.AwayGoals < 0 {
+			return league, fmt.Errorf("match %d: goals cannot be negative", p.MatchID)
+		}
+		seen[p.MatchID] = true
+		league.Matches[i].SetResult(p.HomeGoals, p.AwayGoals)
+	}
+	return league, nil
+}
+
+// whatIfScenario runs the Monte Carlo engine with some unplayed results
+// fixed and compares it with the baseline. Nothing is stored; both runs use
+// the same seed so the difference comes from the pinned results alone.
+// ?simulations=, ?seed=, ?zones= and ?from_week= work as for
+// position-probabilities.
+func whatIfScenario(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Results []PinnedResultJSON `json:"results"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	mc, err := monteCarloFromRequest(r, defaultSimulations)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	league, _, ok := estimateLeague(w, r, -1)
+	if !ok {
+		return
+	}
+	zones, err := zonesFromRequest(r, len(league.Teams))
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	scenario, err := pinResults(league, req.Results)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	pinned := []MatchJSON{}
+	for _, p := range req.Results {
+		for _, m := range scenario.Matches {
+			if m.ID == p.MatchID {
+				pinned = append(pinned, matchToJSON(m))
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"simulations": mc.Simulations,
+		"seed":        mc.Seed,
+		"zones":       zonesToJSON(zones),
+		"pinned":      pinned,
+		"baseline": map[string]interface{}{
+			"table":         buildStandings(league.CalculateTable()),
+			"probabilities": forecastToJSON(mc.Run(league), zones),
+		},
+		"scenario": map[string]interface{}{
+			"table":         buildStandings(scenario.CalculateTable()),
+			"probabilities": forecastToJSON(mc.Run(scenario), zones),
+		},
+	})
+}
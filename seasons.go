@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Case_study/models"
+)
+
+type SeasonJSON struct {
+	ID       int    `json:"id"`
+	LeagueID int    `json:"league_id"`
+	Name     string `json:"name"`
+	Closed   bool   `json:"closed"`
+}
+
+func seasonToJSON(s models.Season) SeasonJSON {
+	return SeasonJSON{ID: s.ID, LeagueID: s.LeagueID, Name: s.Name, Closed: s.Closed}
+}
+
+// closeSeason archives the final table of the season in progress and opens
+// a new one with fresh fixtures. Ratings carry over into the new season.
+func closeSeason(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", 400)
+			return
+		}
+	}
+	league, err := leagueRepo.GetLeague(leagueIDFromRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	for _, m := range league.Matches {
+		if !m.Played {
+			http.Error(w, "Season still has unplayed matches", http.StatusConflict)
+			return
+		}
+	}
+	if err := rateLeague(&league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if err := leagueRepo.UpdateLeague(league); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	seasonRepo := models.SQLiteSeasonRepository{}
+	table := league.CalculateTable()
+	seasons, err := seasonRepo.GetSeasonsByLeague(league.ID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Season " + strconv.Itoa(len(seasons)+1)
+	}
+	// The new season starts from the ratings the old one finished with
+	var start []models.RatingPoint
+	for _, t := range league.Teams {
+		start = append(start, models.RatingPoint{TeamID: t.ID, Week: 0, Rating: t.Rating})
+	}
+	fixtures := models.GenerateDoubleRoundRobin(league.Teams)
+	seasonID, err := seasonRepo.StartNextSeason(league.SeasonID, table, league.ID, name, fixtures, league.Teams, start)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	season, err := seasonRepo.GetSeason(seasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"closed_season_id": league.SeasonID,
+		"final_table":      table,
+		"new_season":       seasonToJSON(season),
+	})
+}
+
+// listSeasons returns every season, or only one league's with ?league_id=
+func listSeasons(w http.ResponseWriter, r *http.Request) {
+	seasonRepo := models.SQLiteSeasonRepository{}
+	var seasons []models.Season
+	var err error
+	if v := r.URL.Query().Get("league_id"); v != "" {
+		leagueID, convErr := strconv.Atoi(v)
+		if convErr != nil {
+			http.Error(w, "Invalid league ID", 400)
+			return
+		}
+		seasons, err = seasonRepo.GetSeasonsByLeague(leagueID)
+	} else {
+		seasons, err = seasonRepo.GetAllSeasons()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	result := []SeasonJSON{}
+	for _, s := range seasons {
+		result = append(result, seasonToJSON(s))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// seasonArchive serves /seasons/{id}/table and /seasons/{id}/results
+func seasonArchive(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/seasons/"):], "/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid season ID", 400)
+		return
+	}
+	seasonRepo := models.SQLiteSeasonRepository{}
+	season, err := seasonRepo.GetSeason(id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Season not found", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	switch parts[1] {
+	case "table":
+		seasonTable(w, season)
+	case "results":
+		seasonResults(w, season)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func seasonTable(w http.ResponseWriter, season models.Season) {
+	var table interface{}
+	if season.Closed {
+		archived, err := models.SQLiteSeasonRepository{}.GetArchivedTable(season.ID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		table = archived
+	} else {
+		league, err := leagueRepo.GetLeague(season.LeagueID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		table = league.CalculateTable()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"season": seasonToJSON(season),
+		"table":  table,
+	})
+}
+
+func seasonResults(w http.ResponseWriter, season models.Season) {
+	teamRepo := models.SQLiteTeamRepository{}
+	teams, err := teamRepo.GetTeamsByLeague(season.LeagueID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	matchRepo := models.SQLiteMatchRepository{}
+	matches, err := matchRepo.GetMatchesBySeason(season.ID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	teamNames := make(map[int]string)
+	for _, t := range teams {
+		teamNames[t.ID] = t.Name
+	}
+	results := make(map[int][]string)
+	for _, m := range matches {
+		if m.Played && m.HomeGoals.Valid && m.AwayGoals.Valid {
+			res := teamNames[m.HomeTeamID] + " " + strconv.Itoa(int(m.HomeGoals.Int64)) + " - " + strconv.Itoa(int(m.AwayGoals.Int64)) + " " + teamNames[m.AwayTeamID]
+			results[m.Week] = append(results[m.Week], res)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"season":          seasonToJSON(season),
+		"results_by_week": results,
+	})
+}
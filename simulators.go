@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Case_study/models"
+)
+
+type SimulatorParamJSON struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Default     float64 `json:"default"`
+	Min         float64 `json:"min"`
+	Max         float64 `json:"max"`
+	Integer     bool    `json:"integer"`
+}
+
+type SimulatorJSON struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Params      []SimulatorParamJSON `json:"params"`
+}
+
+// simulatorNames lists the registered simulators for the -simulator usage
+func simulatorNames() []string {
+	names := []string{}
+	for _, spec := range simulators.Specs() {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+// simulatorParamFlags defines a flag for every simulator parameter, named
+// with dashes: home_advantage is -home-advantage. A flag only applies when
+// it is set, so the simulator's own default stays in place otherwise.
+func simulatorParamFlags(fs *flag.FlagSet) map[string]*float64 {
+	flags := make(map[string]*float64)
+	specs := simulators.Specs()
+	for _, name := range simulators.ParamNames() {
+		var takenBy, defaults []string
+		description := ""
+		for _, spec := range specs {
+			if p, ok := spec.Param(name); ok {
+				takenBy = append(takenBy, spec.Name)
+				defaults = append(defaults, fmt.Sprintf("%s %g", spec.Name, p.Default))
+				description = p.Description
+			}
+		}
+		usage := description + " (default " + strings.Join(defaults, ", ") + ")"
+		if len(takenBy) == len(specs) {
+			usage = description + " (default " + strings.TrimPrefix(defaults[0], takenBy[0]+" ") + ", any simulator)"
+		}
+		flags[name] = fs.Float64(strings.ReplaceAll(name, "_", "-"), 0, usage)
+	}
+	return flags
+}
+
+// setSimulatorParams returns the values of the parameter flags that were
+// set on the command line
+func setSimulatorParams(fs *flag.FlagSet, flags map[string]*float64) models.SimulatorParams {
+	params := make(models.SimulatorParams)
+	fs.Visit(func(f *flag.Flag) {
+		name := strings.ReplaceAll(f.Name, "-", "_")
+		if v, ok := flags[name]; ok {
+			params[name] = *v
+		}
+	})
+	return params
+}
+
+// requestSimulatorConfig reads the simulator a request selects: ?model=,
+// defaulting to model or, when that is empty, to the configured simulator,
+// and any of its parameters given in the query. The configured parameters
+// carry over as long as the model stays the same. changed reports whether
+// the request selected anything.
+func requestSimulatorConfig(r *http.Request, model string) (config models.SimulatorConfig, changed bool, err error) {
+	q := r.URL.Query()
+	if model == "" {
+		model = simulatorConfig.Model
+	}
+	if v := q.Get("model"); v != "" {
+		model, changed = v, true
+	}
+	config = models.SimulatorConfig{Model: model, Params: make(models.SimulatorParams)}
+	if model == simulatorConfig.Model {
+		for name, v := range simulatorConfig.Params {
+			config.Params[name] = v
+		}
+	}
+	for _, name := range simulators.ParamNames() {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return config, changed, fmt.Errorf("%s must be a number", name)
+		}
+		config.Params[name], changed = f, true
+	}
+	return config, changed, nil
+}
+
+// simulatorFromRequest returns the configured simulator, or the one ?model=
+// and the parameters in the query select, e.g.
+// ?model=poisson&home_advantage=1.3
+func simulatorFromRequest(r *http.Request) (models.MatchSimulator, error) {
+	config, changed, err := requestSimulatorConfig(r, "")
+	if err != nil || !changed {
+		return matchSim, err
+	}
+	sim, _, err := simulators.Build(config)
+	return sim, err
+}
+
+// listSimulators describes every registered simulator with its parameters
+// and the simulator configured at startup with the values it uses
+func listSimulators(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	_, values, err := simulators.Resolve(simulatorConfig)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	result := []SimulatorJSON{}
+	for _, spec := range simulators.Specs() {
+		params := []SimulatorParamJSON{}
+		for _, p := range spec.AllParams() {
+			params = append(params, SimulatorParamJSON{
+				Name:        p.Name,
+				Description: p.Description,
+				Default:     p.Default,
+				Min:         p.Min,
+				Max:         p.Max,
+				Integer:     p.Integer,
+			})
+		}
+		result = append(result, SimulatorJSON{Name: spec.Name, Description: spec.Description, Params: params})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"configured": map[string]interface{}{
+			"model":  simulatorConfig.Model,
+			"params": values,
+		},
+		"simulators": result,
+	})
+}
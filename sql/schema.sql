@@ -1,30 +1,148 @@
+-- Leagues table
+CREATE TABLE leagues (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    tie_breakers TEXT NOT NULL DEFAULT 'goal_difference,goals_for,name',
+    lots_seed INTEGER NOT NULL DEFAULT 0
+);
+
+-- Points system per league; leagues without a row use 3/1/0
+CREATE TABLE scoring_rules (
+    league_id INTEGER PRIMARY KEY,
+    win_points INTEGER NOT NULL,
+    draw_points INTEGER NOT NULL,
+    loss_points INTEGER NOT NULL,
+    goals_bonus_threshold INTEGER NOT NULL DEFAULT 0,
+    goals_bonus_points INTEGER NOT NULL DEFAULT 0,
+    losing_bonus_margin INTEGER NOT NULL DEFAULT 0,
+    losing_bonus_points INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY(league_id) REFERENCES leagues(id)
+);
+
 -- Teams table
 CREATE TABLE teams (
     id INTEGER PRIMARY KEY,
+    league_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    strength INTEGER NOT NULL,
+    rating REAL,
+    fair_play_points INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY(league_id) REFERENCES leagues(id)
+);
+
+-- Squads; starting marks the chosen lineup
+CREATE TABLE players (
+    id INTEGER PRIMARY KEY,
+    team_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    position TEXT NOT NULL,
+    attack INTEGER NOT NULL,
+    defence INTEGER NOT NULL,
+    goalkeeping INTEGER NOT NULL,
+    starting BOOLEAN NOT NULL DEFAULT 0,
+    FOREIGN KEY(team_id) REFERENCES teams(id)
+);
+
+-- Injuries and suspensions entered by hand; the ones that come from
+-- matches are worked out from match_events
+CREATE TABLE player_absences (
+    id INTEGER PRIMARY KEY,
+    player_id INTEGER NOT NULL,
+    season_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    from_week INTEGER NOT NULL,
+    weeks INTEGER NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY(player_id) REFERENCES players(id),
+    FOREIGN KEY(season_id) REFERENCES seasons(id)
+);
+
+-- Seasons table
+CREATE TABLE seasons (
+    id INTEGER PRIMARY KEY,
+    league_id INTEGER NOT NULL,
     name TEXT NOT NULL,
-    strength INTEGER NOT NULL
+    closed BOOLEAN NOT NULL DEFAULT 0,
+    FOREIGN KEY(league_id) REFERENCES leagues(id)
+);
+
+-- Point deductions (sanctions) for a team in a season
+CREATE TABLE point_deductions (
+    id INTEGER PRIMARY KEY,
+    season_id INTEGER NOT NULL,
+    team_id INTEGER NOT NULL,
+    points INTEGER NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY(season_id) REFERENCES seasons(id),
+    FOREIGN KEY(team_id) REFERENCES teams(id)
 );
 
 -- Matches table
 CREATE TABLE matches (
     id INTEGER PRIMARY KEY,
+    season_id INTEGER NOT NULL,
     home_team_id INTEGER NOT NULL,
     away_team_id INTEGER NOT NULL,
     home_goals INTEGER,
     away_goals INTEGER,
     week INTEGER NOT NULL,
     played BOOLEAN NOT NULL DEFAULT 0,
+    -- scheduled, live, completed, postponed, abandoned or awarded; played
+    -- is 1 exactly when the status is completed or awarded
+    status TEXT NOT NULL DEFAULT 'scheduled',
+    FOREIGN KEY(season_id) REFERENCES seasons(id),
     FOREIGN KEY(home_team_id) REFERENCES teams(id),
     FOREIGN KEY(away_team_id) REFERENCES teams(id)
 );
 
--- League table (standings)
+-- Lineups, goals, cards, injuries and substitutions of a match
+CREATE TABLE match_events (
+    id INTEGER PRIMARY KEY,
+    match_id INTEGER NOT NULL,
+    minute INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    team_id INTEGER NOT NULL,
+    player_id INTEGER,
+    player TEXT NOT NULL DEFAULT '',
+    -- the goal's assist or the substitute coming on
+    related_player_id INTEGER,
+    related_player TEXT NOT NULL DEFAULT '',
+    -- weeks an injury rules the player out
+    weeks INTEGER NOT NULL DEFAULT 0,
+    extra TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY(match_id) REFERENCES matches(id),
+    FOREIGN KEY(player_id) REFERENCES players(id),
+    FOREIGN KEY(related_player_id) REFERENCES players(id),
+    FOREIGN KEY(team_id) REFERENCES teams(id)
+);
+
+-- League table (standings), one row per team per season
 CREATE TABLE league_table (
-    team_id INTEGER PRIMARY KEY,
+    season_id INTEGER NOT NULL,
+    team_id INTEGER NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
     points INTEGER NOT NULL,
     goals_for INTEGER NOT NULL,
     goals_against INTEGER NOT NULL,
     goal_difference INTEGER NOT NULL,
     matches_played INTEGER NOT NULL,
+    wins INTEGER NOT NULL DEFAULT 0,
+    draws INTEGER NOT NULL DEFAULT 0,
+    losses INTEGER NOT NULL DEFAULT 0,
+    away_goals_for INTEGER NOT NULL DEFAULT 0,
+    points_deducted INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY(season_id, team_id),
+    FOREIGN KEY(season_id) REFERENCES seasons(id),
+    FOREIGN KEY(team_id) REFERENCES teams(id)
+); 
+
+-- Team rating history (Elo), one row per team per week of each season
+CREATE TABLE team_ratings (
+    season_id INTEGER NOT NULL,
+    team_id INTEGER NOT NULL,
+    week INTEGER NOT NULL,
+    rating REAL NOT NULL,
+    PRIMARY KEY(season_id, team_id, week),
+    FOREIGN KEY(season_id) REFERENCES seasons(id),
     FOREIGN KEY(team_id) REFERENCES teams(id)
-); 
\ No newline at end of file
+);
\ No newline at end of file
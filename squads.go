@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Case_study/models"
+)
+
+type PlayerJSON struct {
+	ID          int    `json:"id"`
+	TeamID      int    `json:"team_id"`
+	Name        string `json:"name"`
+	Position    string `json:"position"`
+	Attack      int    `json:"attack"`
+	Defence     int    `json:"defence"`
+	Goalkeeping int    `json:"goalkeeping"`
+	Starting    bool   `json:"starting"`
+}
+
+func playerToJSON(p models.Player) PlayerJSON {
+	return PlayerJSON{
+		ID:          p.ID,
+		TeamID:      p.TeamID,
+		Name:        p.Name,
+		Position:    string(p.Position),
+		Attack:      p.Attack,
+		Defence:     p.Defence,
+		Goalkeeping: p.Goalkeeping,
+		Starting:    p.Starting,
+	}
+}
+
+// generateSquads stores a made-up squad for each team
+func generateSquads(teams []models.Team, rng *rand.Rand) error {
+	playerRepo := models.SQLitePlayerRepository{}
+	for _, t := range teams {
+		for _, p := range models.GenerateSquad(t, rng) {
+			if _, err := playerRepo.CreatePlayer(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// squadRoutes serves /teams/{id}/squad: GET shows the squad with the
+// lineup that would play, POST adds a player, PUT lineup picks the
+// starting eleven and POST generate makes up a squad for a team without
+// one
+func squadRoutes(w http.ResponseWriter, r *http.Request, team models.Team, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		writeSquad(w, team.ID)
+	case len(rest) == 0 && r.Method == http.MethodPost:
+		createPlayer(w, r, team)
+	case len(rest) == 1 && rest[0] == "lineup" && r.Method == http.MethodPut:
+		setLineup(w, r, team)
+	case len(rest) == 1 && rest[0] == "generate" && r.Method == http.MethodPost:
+		generateSquad(w, r, team)
+	case len(rest) == 0 || (len(rest) == 1 && (rest[0] == "lineup" || rest[0] == "generate")):
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeSquad responds with the squad, the players ruled out in the next
+// week to be played, and the starting eleven and the attack and defence
+// the squad simulator would use that week
+func writeSquad(w http.ResponseWriter, teamID int) {
+	team, err := models.SQLiteTeamRepository{}.GetTeamByID(teamID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	league, err := leagueRepo.GetLeague(team.LeagueID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	avail, err := models.LoadAvailability(league.SeasonID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	week := nextUnplayedWeek(league.Matches)
+	squad := getTeamByID(league.Teams, teamID).Players
+	available := getTeamByID(avail.Teams(league, week), teamID).Players
+	players := []PlayerJSON{}
+	for _, p := range squad {
+		players = append(players, playerToJSON(p))
+	}
+	availableIDs := make(map[int]bool)
+	for _, p := range available {
+		availableIDs[p.ID] = true
+	}
+	unavailable := []int{}
+	for _, p := range squad {
+		if !availableIDs[p.ID] {
+			unavailable = append(unavailable, p.ID)
+		}
+	}
+	eleven := models.StartingEleven(available)
+	elevenIDs := []int{}
+	for _, p := range eleven {
+		elevenIDs = append(elevenIDs, p.ID)
+	}
+	result := map[string]interface{}{
+		"team_id":         teamID,
+		"week":            week,
+		"players":         players,
+		"unavailable":     unavailable,
+		"starting_eleven": elevenIDs,
+	}
+	if attack, defence, ok := models.LineupStrength(eleven); ok {
+		result["attack"] = attack
+		result["defence"] = defence
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func createPlayer(w http.ResponseWriter, r *http.Request, team models.Team) {
+	var req PlayerJSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	player := models.Player{
+		TeamID:      team.ID,
+		Name:        strings.TrimSpace(req.Name),
+		Position:    models.Position(strings.ToUpper(req.Position)),
+		Attack:      req.Attack,
+		Defence:     req.Defence,
+		Goalkeeping: req.Goalkeeping,
+	}
+	if err := player.Validate(); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	playerRepo := models.SQLitePlayerRepository{}
+	id, err := playerRepo.CreatePlayer(player)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	player.ID = id
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(playerToJSON(player))
+}
+
+// setLineup takes {"player_ids": [...]} with eleven players including one
+// goalkeeper; an empty list goes back to picking the lineup automatically
+func setLineup(w http.ResponseWriter, r *http.Request, team models.Team) {
+	var req struct {
+		PlayerIDs []int `json:"player_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	playerRepo := models.SQLitePlayerRepository{}
+	if len(req.PlayerIDs) > 0 {
+		squad, err := playerRepo.GetPlayersByTeam(team.ID)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		if err := models.ValidateLineup(squad, req.PlayerIDs); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+	}
+	if err := playerRepo.SetLineup(team.ID, req.PlayerIDs); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	writeSquad(w, team.ID)
+}
+
+// generateSquad makes up an 18-player squad rated around the team's
+// strength. ?seed= makes it reproducible.
+func generateSquad(w http.ResponseWriter, r *http.Request, team models.Team) {
+	seed := time.Now().UnixNano()
+	if v := r.URL.Query().Get("seed"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid seed", 400)
+			return
+		}
+		seed = n
+	}
+	squad, err := models.SQLitePlayerRepository{}.GetPlayersByTeam(team.ID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if len(squad) > 0 {
+		http.Error(w, "Team already has a squad", http.StatusConflict)
+		return
+	}
+	if err := generateSquads([]models.Team{team}, rand.New(rand.NewSource(seed))); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	writeSquad(w, team.ID)
+}
+
+// playerRoutes serves /players/{id}: GET, PUT or PATCH to change name,
+// position or ratings, and DELETE. /players/{id}/absences manages injuries
+// and suspensions.
+func playerRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/players/"):], "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid player ID", 400)
+		return
+	}
+	playerRepo := models.SQLitePlayerRepository{}
+	player, err := playerRepo.GetPlayerByID(id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Player not found", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if len(parts) > 1 && parts[1] == "absences" && len(parts) <= 3 {
+		playerAbsences(w, r, player, parts[2:])
+		return
+	}
+	if len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(playerToJSON(player))
+	case http.MethodPut, http.MethodPatch:
+		updatePlayer(w, r, player)
+	case http.MethodDelete:
+		if err := playerRepo.DeletePlayer(player.ID); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func updatePlayer(w http.ResponseWriter, r *http.Request, player models.Player) {
+	var req struct {
+		Name        *string `json:"name"`
+		Position    *string `json:"position"`
+		Attack      *int    `json:"attack"`
+		Defence     *int    `json:"defence"`
+		Goalkeeping *int    `json:"goalkeeping"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	if req.Name != nil {
+		player.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Position != nil {
+		player.Position = models.Position(strings.ToUpper(*req.Position))
+	}
+	if req.Attack != nil {
+		player.Attack = *req.Attack
+	}
+	if req.Defence != nil {
+		player.Defence = *req.Defence
+	}
+	if req.Goalkeeping != nil {
+		player.Goalkeeping = *req.Goalkeeping
+	}
+	if err := player.Validate(); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if err := (models.SQLitePlayerRepository{}).UpdatePlayer(player); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(playerToJSON(player))
+}
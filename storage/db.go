@@ -9,6 +9,14 @@ import (
 
 var db *sql.DB
 
+// DBTX is satisfied by both *sql.DB and *sql.Tx so queries can run inside
+// or outside a transaction
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 func InitDB(filepath string, schemaPath string) {
 	var err error
 	db, err = sql.Open("sqlite3", filepath)
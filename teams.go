@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"Case_study/models"
+)
+
+func teamToJSON(t models.Team) TeamJSON {
+	return TeamJSON{ID: t.ID, LeagueID: t.LeagueID, Name: t.Name, Strength: t.Strength, Rating: t.Rating}
+}
+
+// teamsHandler serves /teams: GET lists teams, optionally only one league's
+// with ?league_id=, and POST adds a team to a league
+func teamsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listTeams(w, r)
+	case http.MethodPost:
+		createTeam(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func listTeams(w http.ResponseWriter, r *http.Request) {
+	teamRepo := models.SQLiteTeamRepository{}
+	var teams []models.Team
+	var err error
+	if v := r.URL.Query().Get("league_id"); v != "" {
+		leagueID, convErr := strconv.Atoi(v)
+		if convErr != nil {
+			http.Error(w, "Invalid league ID", 400)
+			return
+		}
+		teams, err = teamRepo.GetTeamsByLeague(leagueID)
+	} else {
+		teams, err = teamRepo.GetAllTeams()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	result := []TeamJSON{}
+	for _, t := range teams {
+		result = append(result, teamToJSON(t))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func createTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		LeagueID int    `json:"league_id"`
+		Name     string `json:"name"`
+		Strength int    `json:"strength"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	if req.LeagueID == 0 {
+		req.LeagueID = defaultLeagueID
+	}
+	team := models.Team{LeagueID: req.LeagueID, Name: strings.TrimSpace(req.Name), Strength: req.Strength}
+	if err := team.Validate(); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	league, err := leagueRepo.GetLeague(team.LeagueID)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "League not found", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if nameTaken(league.Teams, team.Name, 0) {
+		http.Error(w, "A team with that name already exists in the league", http.StatusConflict)
+		return
+	}
+	teamRepo := models.SQLiteTeamRepository{}
+	id, err := teamRepo.CreateTeam(team)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if err := refreshLeagueTeams(team.LeagueID, id, true); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	created, err := teamRepo.GetTeamByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(teamToJSON(created))
+}
+
+// teamRoutes serves /teams/{id}: GET, PUT or PATCH to rename or change
+// strength, and DELETE. /teams/{id}/squad/... manages the team's players
+// and GET /teams/{id}/form shows recent results.
+func teamRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path[len("/teams/"):], "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.Error(w, "Invalid team ID", 400)
+		return
+	}
+	teamRepo := models.SQLiteTeamRepository{}
+	team, err := teamRepo.GetTeamByID(id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Team not found", 404)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "form" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		teamForm(w, r, team)
+		return
+	}
+	if len(parts) > 1 && parts[1] == "squad" {
+		squadRoutes(w, r, team, parts[2:])
+		return
+	}
+	if len(parts) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(teamToJSON(team))
+	case http.MethodPut, http.MethodPatch:
+		updateTeam(w, r, team)
+	case http.MethodDelete:
+		deleteTeam(w, team)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func updateTeam(w http.ResponseWriter, r *http.Request, team models.Team) {
+	var req struct {
+		Name     *string `json:"name"`
+		Strength *int    `json:"strength"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", 400)
+		return
+	}
+	if req.Name != nil {
+		team.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Strength != nil {
+		team.Strength = *req.Strength
+	}
+	if err := team.Validate(); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	teamRepo := models.SQLiteTeamRepository{}
+	teams, err := teamRepo.GetTeamsByLeague(team.LeagueID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if nameTaken(teams, team.Name, team.ID) {
+		http.Error(w, "A team with that name already exists in the league", http.StatusConflict)
+		return
+	}
+	if err := teamRepo.UpdateTeam(team); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if err := refreshLeagueTeams(team.LeagueID, team.ID, false); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	updated, err := teamRepo.GetTeamByID(team.ID)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(teamToJSON(updated))
+}
+
+func deleteTeam(w http.ResponseWriter, team models.Team) {
+	err := models.SQLiteTeamRepository{}.DeleteTeam(team.ID)
+	if errors.Is(err, models.ErrTeamHasPlayed) {
+		http.Error(w, "Team has matches that have started and cannot be deleted", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if err := refreshLeagueTeams(team.LeagueID, 0, true); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// nameTaken reports whether another team already uses the name
+func nameTaken(teams []models.Team, name string, exceptID int) bool {
+	for _, t := range teams {
+		if t.ID != exceptID && strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshLeagueTeams brings the current season in line with a changed
+// team. When a team was added or removed (fixturesChanged) before the
+// season started, the fixtures are regenerated; otherwise the existing
+// fixtures stand. Before the start the changed team's starting rating
+// follows its strength. Standings and ratings are rewritten either way.
+func refreshLeagueTeams(leagueID int, changedTeamID int, fixturesChanged bool) error {
+	league, err := leagueRepo.GetLeague(leagueID)
+	if err != nil {
+		return err
+	}
+	started, err := seasonStarted(league)
+	if err != nil {
+		return err
+	}
+	if fixturesChanged && !started {
+		if err := createFixtures(league.ID, league.SeasonID); err != nil {
+			return err
+		}
+		if league, err = leagueRepo.GetLeague(leagueID); err != nil {
+			return err
+		}
+	}
+	start, err := models.SQLiteRatingRepository{}.GetStartRatings(league.SeasonID)
+	if err != nil {
+		return err
+	}
+	if !started {
+		for _, t := range league.Teams {
+			if t.ID == changedTeamID {
+				start[t.ID] = models.InitialRating(t.Strength)
+			}
+		}
+	}
+	league.RatingHistory = rateTeams(&league, start)
+	return leagueRepo.UpdateLeague(league)
+}
+
+// seasonStarted reports whether anything has happened to the season's
+// fixtures that regenerating them would lose: a match that is no longer
+// plainly scheduled (played, live, postponed, ...) or one with recorded
+// events
+func seasonStarted(league models.League) (bool, error) {
+	for _, m := range league.Matches {
+		if m.Status != models.StatusScheduled {
+			return true, nil
+		}
+	}
+	events, err := models.SQLiteEventRepository{}.GetEventsBySeason(league.SeasonID)
+	if err != nil {
+		return false, err
+	}
+	return len(events) > 0, nil
+}